@@ -0,0 +1,203 @@
+package backend
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RefLast refers to the last entry in the timesheet, see FindEntry
+const RefLast = "last"
+
+// positionRe matches a position from the end of the timesheet, ie: -2
+var positionRe = regexp.MustCompile(`^-(\d+)$`)
+
+// FindEntry returns the entry that ref refers to, which is one of:
+//
+//	an entry ID or the start of one, ie: 3f2a
+//	last         the last entry
+//	-1, -2, ...  the last entry, the one before it, ...
+func (b *Backend) FindEntry(ctx context.Context, ref string) (*SavedEntry, error) {
+	fileLock, err := b.rlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer fileLock.Unlock()
+	entries, err := b.store.List(time.Time{}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	i, err := resolveRef(entries, ref)
+	if err != nil {
+		return nil, err
+	}
+	return &entries[i], nil
+}
+
+// AmendEntry changes the entry that ref refers to with opts, see FindEntry
+// An entry moved with EntryAt is kept in chronological order.  The
+// timesheet is backed up before it is changed, and the change is recorded
+// in the journal as a move, retitle or amend.
+func (b *Backend) AmendEntry(ctx context.Context, ref string, opts ...EntryOption) (*SavedEntry, error) {
+	fileLock, err := b.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer fileLock.Unlock()
+	entries, err := b.store.List(time.Time{}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	i, err := resolveRef(entries, ref)
+	if err != nil {
+		return nil, err
+	}
+	entry := entries[i]
+	for _, opt := range opts {
+		opt(&entry)
+	}
+	entry.ID = entries[i].ID
+	err = b.validateEntry(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	before := entries[i]
+	kind := OpAmend
+	switch {
+	case !entry.End.Equal(before.End):
+		kind = OpMove
+	case entry.Task != before.Task:
+		kind = OpRetitle
+	}
+
+	err = b.backup()
+	if err != nil {
+		return nil, err
+	}
+	if kind == OpMove {
+		entries = append(entries[:i], entries[i+1:]...)
+		err = b.store.ReplaceAll(insertEntry(entries, entry))
+	} else {
+		err = b.store.Update(entry)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry, b.record(kind, Change{ID: entry.ID, Before: &before, After: &entry})
+}
+
+// RetitleEntry replaces the task of the entry that ref refers to, see
+// FindEntry.  Words in args that start with + or # set the project and
+// add tags, like they do in Add.
+func (b *Backend) RetitleEntry(ctx context.Context, ref string, args []string) (*SavedEntry, error) {
+	task, err := parseTask(strings.Join(args, " "), b.markers())
+	if err != nil {
+		return nil, err
+	}
+	opts := []EntryOption{entryTask(task.format(b.markers())), EntryTags(task.Tags...)}
+	if task.Project != "" {
+		opts = append(opts, EntryProject(task.Project))
+	}
+	return b.AmendEntry(ctx, ref, opts...)
+}
+
+// RemoveEntries removes the entries that refs refer to, see FindEntry,
+// and returns them.  Every ref is resolved before anything is removed, so
+// -1 -2 removes the last two entries.  The timesheet is backed up first.
+func (b *Backend) RemoveEntries(ctx context.Context, refs ...string) ([]SavedEntry, error) {
+	fileLock, err := b.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer fileLock.Unlock()
+	entries, err := b.store.List(time.Time{}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	removed := []SavedEntry{}
+	seen := map[string]bool{}
+	for _, ref := range refs {
+		i, err := resolveRef(entries, ref)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[entries[i].ID] {
+			seen[entries[i].ID] = true
+			removed = append(removed, entries[i])
+		}
+	}
+
+	err = b.backup()
+	if err != nil {
+		return nil, err
+	}
+	changes := []Change{}
+	for _, e := range removed {
+		err = b.store.Delete(e.ID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "removing %s", e.ID)
+		}
+		changes = append(changes, Change{ID: e.ID, Before: entryPtr(e)})
+	}
+	return removed, b.record(OpRemove, changes...)
+}
+
+// validateEntry checks an entry before it is saved
+func (b *Backend) validateEntry(entry SavedEntry) error {
+	if entry.Task == "" {
+		return errors.Wrap(ErrInvalidEntry, "missing task description")
+	}
+	if entry.End.IsZero() {
+		return errors.Wrap(ErrInvalidEntry, "missing end time")
+	}
+	if _, ok := b.category(entry.Category); entry.Category != "" && !ok {
+		return errors.Wrapf(ErrInvalidEntry, "unknown category %q", entry.Category)
+	}
+	return nil
+}
+
+// resolveRef returns the index in entries of the entry that ref refers
+// to, see FindEntry
+func resolveRef(entries []SavedEntry, ref string) (int, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return 0, errors.New("missing entry ID or position")
+	}
+	n := 0
+	if strings.EqualFold(ref, RefLast) {
+		n = 1
+	} else if m := positionRe.FindStringSubmatch(ref); m != nil {
+		n, _ = strconv.Atoi(m[1])
+		if n == 0 {
+			return 0, errors.Errorf("invalid position %s - -1 is the last entry", ref)
+		}
+	}
+	if n > 0 {
+		if n > len(entries) {
+			return 0, errors.Wrapf(ErrNotFound, "%s is before the first of %d entries", ref, len(entries))
+		}
+		return len(entries) - n, nil
+	}
+
+	found := -1
+	for i, e := range entries {
+		if e.ID == ref {
+			return i, nil
+		}
+		if strings.HasPrefix(e.ID, ref) {
+			if found >= 0 {
+				return 0, errors.Errorf("%q is the start of more than one entry ID - use more of the ID", ref)
+			}
+			found = i
+		}
+	}
+	if found < 0 {
+		return 0, errors.Wrapf(ErrNotFound, "no entry ID starts with %q", ref)
+	}
+	return found, nil
+}
@@ -0,0 +1,94 @@
+package backend
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func Test_resolveRef(t *testing.T) {
+	entries := []SavedEntry{{ID: "3f2a-1"}, {ID: "3f2b-2"}, {ID: "9c1e-3"}}
+	tests := []struct {
+		name     string
+		ref      string
+		want     int
+		wantErr  bool
+		notFound bool
+	}{
+		{"id", "3f2b-2", 1, false, false},
+		{"prefix", "9c", 2, false, false},
+		{"ambiguous prefix", "3f2", 0, true, false},
+		{"unknown", "abc", 0, true, true},
+		{"last", "last", 2, false, false},
+		{"last upper case", "LAST", 2, false, false},
+		{"last position", "-1", 2, false, false},
+		{"position", "-3", 0, false, false},
+		{"before first", "-4", 0, true, true},
+		{"zero position", "-0", 0, true, false},
+		{"empty", " ", 0, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveRef(entries, tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveRef() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (errors.Cause(err) == ErrNotFound) != tt.notFound {
+				t.Errorf("resolveRef() error = %v, want not found %v", err, tt.notFound)
+			}
+			if got != tt.want {
+				t.Errorf("resolveRef() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackend_AmendEntry(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	entries := []SavedEntry{
+		{ID: "a", End: day.Add(9 * time.Hour), Task: "hello"},
+		{ID: "b", End: day.Add(10 * time.Hour), Task: "email"},
+		{ID: "c", End: day.Add(11 * time.Hour), Task: "review", Project: "omw", Tags: []string{"code"}},
+	}
+	b, cleanup := newTestBackend(t, entries)
+	defer cleanup()
+
+	got, err := b.AmendEntry(ctx, "last", EntryUntag("code"), EntryTags("pr"), EntryBillable(true))
+	if err != nil {
+		t.Fatalf("AmendEntry() error = %v", err)
+	}
+	want := SavedEntry{ID: "c", End: day.Add(11 * time.Hour), Task: "review", Project: "omw", Tags: []string{"pr"}, Billable: true}
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("AmendEntry() = %v, want %v", *got, want)
+	}
+	if _, err := b.AmendEntry(ctx, "b", EntryCategory("nap")); err == nil {
+		t.Errorf("AmendEntry() unknown category error = nil")
+	}
+
+	// moving b after c keeps the timesheet in order
+	if _, err := b.AmendEntry(ctx, "-2", EntryAt(day.Add(12*time.Hour))); err != nil {
+		t.Fatalf("AmendEntry() move error = %v", err)
+	}
+	if _, err := b.RetitleEntry(ctx, "b", []string{"inbox", "+admin", "#mail"}); err != nil {
+		t.Fatalf("RetitleEntry() error = %v", err)
+	}
+	removed, err := b.RemoveEntries(ctx, "a", "a")
+	if err != nil || len(removed) != 1 {
+		t.Fatalf("RemoveEntries() = %v, %v, want entry a", removed, err)
+	}
+	saved, err := b.Entries(ctx)
+	if err != nil {
+		t.Fatalf("Entries() error = %v", err)
+	}
+	wantSaved := []SavedEntry{
+		want,
+		{ID: "b", End: day.Add(12 * time.Hour), Task: "inbox", Project: "admin", Tags: []string{"mail"}},
+	}
+	if !reflect.DeepEqual(saved, wantSaved) {
+		t.Errorf("Entries() = %v, want %v", saved, wantSaved)
+	}
+}
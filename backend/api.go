@@ -0,0 +1,294 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+// DefaultAddr is the default listen address for omw server
+// The API has no authentication, so it only listens on the loopback interface
+// and only answers requests for a loopback host name, see router.
+const DefaultAddr = "127.0.0.1:31337"
+
+// Serve starts a REST API on addr that exposes the timesheet entries
+// and reports.  addr must be a loopback address.
+// If allowOrigin is not empty, it is sent as the Access-Control-Allow-Origin
+// header so a browser app served from that origin can use the API.
+// Serve blocks until the server fails or ctx is cancelled.
+func (b *Backend) Serve(ctx context.Context, addr, allowOrigin string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return errors.Wrapf(err, "invalid listen address %s", addr)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return errors.Errorf("refusing to listen on non-loopback address %s", addr)
+	}
+
+	srv := &http.Server{
+		Addr:         addr,
+		Handler:      b.router(allowOrigin),
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	err = srv.ListenAndServe()
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// router returns the handler for every API route
+// Requests for another host name are refused, so a web page can't reach
+// the API through DNS rebinding, and entries must be sent as JSON, so a
+// web page can't send them with a simple cross-site form POST.
+func (b *Backend) router(allowOrigin string) http.Handler {
+	r := mux.NewRouter()
+	r.Use(loopbackHostMiddleware)
+	r.HandleFunc("/entries", b.listEntriesHandler).Methods("GET")
+	r.HandleFunc("/entries", requireJSON(b.createEntryHandler)).Methods("POST")
+	r.HandleFunc("/entries/{id}", b.getEntryHandler).Methods("GET")
+	r.HandleFunc("/entries/{id}", requireJSON(b.updateEntryHandler)).Methods("PUT")
+	r.HandleFunc("/entries/{id}", b.deleteEntryHandler).Methods("DELETE")
+	r.HandleFunc("/report", b.reportHandler).Methods("GET")
+	r.HandleFunc("/events", b.eventsHandler).Methods("GET")
+	if allowOrigin != "" {
+		r.Methods("OPTIONS").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+		r.Use(corsMiddleware(allowOrigin))
+	}
+	return r
+}
+
+func (b *Backend) listEntriesHandler(w http.ResponseWriter, r *http.Request) {
+	entries, err := b.Entries(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if entries == nil {
+		entries = []SavedEntry{}
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+func (b *Backend) createEntryHandler(w http.ResponseWriter, r *http.Request) {
+	entry := SavedEntry{}
+	err := json.NewDecoder(r.Body).Decode(&entry)
+	if err != nil {
+		http.Error(w, "invalid entry: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	created, err := b.CreateEntry(r.Context(), entry)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func (b *Backend) getEntryHandler(w http.ResponseWriter, r *http.Request) {
+	entry, err := b.Entry(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, entry)
+}
+
+func (b *Backend) updateEntryHandler(w http.ResponseWriter, r *http.Request) {
+	entry := SavedEntry{}
+	err := json.NewDecoder(r.Body).Decode(&entry)
+	if err != nil {
+		http.Error(w, "invalid entry: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	id := mux.Vars(r)["id"]
+	if entry.ID != "" && entry.ID != id {
+		http.Error(w, "entry ID does not match URL", http.StatusBadRequest)
+		return
+	}
+	entry.ID = id
+	err = b.UpdateEntry(r.Context(), entry)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, entry)
+}
+
+func (b *Backend) deleteEntryHandler(w http.ResponseWriter, r *http.Request) {
+	err := b.DeleteEntry(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// reportHandler accepts the same from, to, period, group_by, grid, rows and
+// format values as omw report, see ParseRange, ParseGroupBy and WithGrid.
+// The dates default to today and the format to json.
+func (b *Backend) reportHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	from := query.Get("from")
+	if from == "" {
+		from = "today"
+	}
+	to := query.Get("to")
+	if to == "" {
+		to = "today"
+	}
+	if period := query.Get("period"); period != "" {
+		from, to = period, period
+	}
+	format := strings.ToLower(query.Get("format"))
+	if format == "" {
+		format = "json"
+	}
+	// check the query first, so the errors of Report are server errors
+	if _, _, err := b.ParseRange(from, to); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	groupBy, err := ParseGroupBy(query.Get("group_by"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts := []ReportOption{GroupBy(groupBy...)}
+	grid := query.Get("grid")
+	if grid != "" {
+		rows := query.Get("rows")
+		if rows == "" {
+			rows = GroupTask
+		}
+		if err := validateGrid(grid, rows); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		opts = append(opts, WithGrid(grid, rows))
+	}
+	switch {
+	case format == "csv" && grid == "":
+		http.Error(w, "the csv format is a timesheet grid - use it with a grid, ie: grid=week", http.StatusBadRequest)
+		return
+	case format != "text" && format != "json" && format != "fc" && format != "csv":
+		http.Error(w, "unknown report format "+format+" - use text, json, fc or csv", http.StatusBadRequest)
+		return
+	}
+	output, err := b.Report(r.Context(), from, to, format, opts...)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	switch format {
+	case "json", "fc":
+		w.Header().Set("Content-Type", "application/json")
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	default:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	w.Write([]byte(output))
+}
+
+// eventsHandler is a FullCalendar JSON event feed
+// FullCalendar requests /events?start=...&end=... with ISO8601 timestamps
+// or dates and an exclusive end
+func (b *Backend) eventsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	start := query.Get("start")
+	end := query.Get("end")
+	if start == "" || end == "" {
+		http.Error(w, "missing start or end query parameter", http.StatusBadRequest)
+		return
+	}
+	// ParseRange treats a date as an inclusive end, so the feed ends where
+	// the end date starts instead, at the day start time like the start
+	from, _, err := b.ParseRange(start, end)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	to, _, err := b.ParseRange(end, end)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	output, err := b.Report(r.Context(), from.Format(time.RFC3339Nano), to.Format(time.RFC3339Nano), "fc")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(output))
+}
+
+// loopbackHostMiddleware refuses requests whose Host header isn't a
+// loopback address or localhost
+func loopbackHostMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		host = strings.Trim(host, "[]")
+		if ip := net.ParseIP(host); !strings.EqualFold(host, "localhost") && (ip == nil || !ip.IsLoopback()) {
+			http.Error(w, "host not allowed", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireJSON refuses requests with a body that isn't application/json
+func requireJSON(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || mediaType != "application/json" {
+			http.Error(w, "Content-Type must be application/json", http.StatusUnsupportedMediaType)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func corsMiddleware(allowOrigin string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Access-Control-Allow-Origin", allowOrigin)
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError maps backend errors to HTTP status codes
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch errors.Cause(err) {
+	case ErrNotFound:
+		status = http.StatusNotFound
+	case ErrInvalidEntry:
+		status = http.StatusBadRequest
+	}
+	http.Error(w, err.Error(), status)
+}
@@ -0,0 +1,145 @@
+package backend
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackend_router(t *testing.T) {
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	entries := []SavedEntry{
+		{ID: "a", End: day.Add(9 * time.Hour), Task: "hello"},
+		{ID: "b", End: day.Add(10 * time.Hour), Task: "email"},
+		{ID: "c", End: day.Add(11 * time.Hour), Task: "review"},
+		{ID: "d", End: day.Add(11*time.Hour + 30*time.Minute), Task: "standup"},
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		host        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantBody    string
+	}{
+		{"list", "GET", "/entries", "", "", "", http.StatusOK, `"id":"b"`},
+		{"get", "GET", "/entries/a", "", "", "", http.StatusOK, `"task":"hello"`},
+		{"get missing", "GET", "/entries/x", "", "", "", http.StatusNotFound, ""},
+		{"create", "POST", "/entries", "", "application/json",
+			`{"id":"e","end":"2020-01-02T11:45:00Z","task":"review"}`, http.StatusCreated, `"id":"e"`},
+		{"create with charset", "POST", "/entries", "", "application/json; charset=utf-8",
+			`{"id":"e","task":"standup"}`, http.StatusCreated, `"end":"2020-01-02T12:00:00Z"`},
+		{"create form", "POST", "/entries", "", "text/plain",
+			`{"task":"review"}`, http.StatusUnsupportedMediaType, ""},
+		{"create without content type", "POST", "/entries", "", "",
+			`{"task":"review"}`, http.StatusUnsupportedMediaType, ""},
+		{"create unknown category", "POST", "/entries", "", "application/json",
+			`{"task":"review","category":"nap"}`, http.StatusBadRequest, "unknown category"},
+		{"create bad json", "POST", "/entries", "", "application/json",
+			`{"task":`, http.StatusBadRequest, ""},
+		{"update", "PUT", "/entries/c", "", "application/json",
+			`{"end":"2020-01-02T11:00:00Z","task":"code review"}`, http.StatusOK, `"task":"code review"`},
+		{"update unknown category", "PUT", "/entries/c", "", "application/json",
+			`{"end":"2020-01-02T11:00:00Z","task":"review","category":"nap"}`, http.StatusBadRequest, ""},
+		{"update form", "PUT", "/entries/c", "", "application/x-www-form-urlencoded",
+			`task=review`, http.StatusUnsupportedMediaType, ""},
+		{"delete", "DELETE", "/entries/d", "", "", "", http.StatusNoContent, ""},
+		{"localhost", "GET", "/entries", "localhost:31337", "", "", http.StatusOK, ""},
+		{"ipv6 loopback", "GET", "/entries", "[::1]:31337", "", "", http.StatusOK, ""},
+		{"rebound host", "GET", "/entries", "attacker.example:31337", "", "", http.StatusForbidden, ""},
+		{"rebound host post", "POST", "/entries", "attacker.example", "application/json",
+			`{"task":"review"}`, http.StatusForbidden, ""},
+		{"report", "GET", "/report?from=2020-01-02&to=2020-01-02", "", "", "", http.StatusOK, `"title":"review"`},
+		{"report bad range", "GET", "/report?from=someday", "", "", "", http.StatusBadRequest, ""},
+		{"report bad group", "GET", "/report?group_by=color", "", "", "", http.StatusBadRequest, ""},
+		{"report bad grid", "GET", "/report?grid=fortnight", "", "", "", http.StatusBadRequest, ""},
+		{"report csv without grid", "GET", "/report?format=csv", "", "", "", http.StatusBadRequest, ""},
+		{"report bad format", "GET", "/report?format=xml", "", "", "", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, cleanup := newTestBackend(t, entries, WithClock(&testClock{now: day.Add(12 * time.Hour)}))
+			defer cleanup()
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			req.Host = "127.0.0.1:31337"
+			if tt.host != "" {
+				req.Host = tt.host
+			}
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			b.router("").ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("%s %s status = %d, want %d: %s", tt.method, tt.target, rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("%s %s body = %s, want it to contain %s", tt.method, tt.target, rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestBackend_eventsHandler(t *testing.T) {
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	b, cleanup := newTestBackend(t, []SavedEntry{
+		{ID: "a", End: day.Add(20 * time.Hour), Task: "hello"},
+		{ID: "b-1", End: day.Add(26 * time.Hour), Task: "night shift", Project: "ops"},
+		{ID: "c", End: day.Add(29 * time.Hour), Task: "wrap up **"},
+	}, WithDayStart(4*time.Hour), WithDayStartHello(true))
+	defer cleanup()
+	handler := b.router("")
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		// wantIDs are the event ID and entry ID of each event
+		wantIDs [][2]string
+	}{
+		{"one day", "start=2020-01-02&end=2020-01-03", http.StatusOK,
+			[][2]string{{"a", "a"}, {"b-1", "b-1"}, {"c-1", "c"}}},
+		{"two days", "start=2020-01-02&end=2020-01-04", http.StatusOK,
+			[][2]string{{"a", "a"}, {"b-1", "b-1"}, {"c-1", "c"}, {"c-2", "c"}}},
+		{"second day", "start=2020-01-03&end=2020-01-04", http.StatusOK, [][2]string{{"c-2", "c"}}},
+		{"timestamps", "start=2020-01-03T04:00:00Z&end=2020-01-04T04:00:00Z", http.StatusOK, [][2]string{{"c-2", "c"}}},
+		{"missing end", "start=2020-01-02", http.StatusBadRequest, nil},
+		{"bad start", "start=someday&end=2020-01-03", http.StatusBadRequest, nil},
+		{"end before start", "start=2020-01-03&end=2020-01-01", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/events?"+tt.query, nil)
+			req.Host = "127.0.0.1:31337"
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("GET /events?%s status = %d, want %d: %s", tt.query, rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if got := rec.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("GET /events Content-Type = %q, want application/json", got)
+			}
+			events := []FCEvent{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &events); err != nil {
+				t.Fatalf("GET /events returned invalid JSON: %v", err)
+			}
+			got := [][2]string{}
+			for _, e := range events {
+				got = append(got, [2]string{e.ID, e.ExtendedProps.EntryID})
+			}
+			if !reflect.DeepEqual(got, tt.wantIDs) {
+				t.Errorf("GET /events?%s = %v, want %v", tt.query, got, tt.wantIDs)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+// writeFileAtomic replaces the file at path on fs with data so that a crash
+// leaves either the old or the new contents on disk, never a mix.
+// data is written to a temporary file in the same directory and synced
+// before it is renamed over path, then the directory is synced so the
+// rename itself is durable.
+// A symlink at path is followed, so the file it points to is replaced
+// instead of the link, and an existing file keeps its permissions.  perm
+// is only used for a new file.
+func writeFileAtomic(fs afero.Fs, path string, data []byte, perm os.FileMode) error {
+	if isOsFs(fs) {
+		if target, err := filepath.EvalSymlinks(path); err == nil {
+			path = target
+		}
+	}
+	if info, err := fs.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	dir := filepath.Dir(path)
+	prefix := fmt.Sprintf(".%s.tmp", filepath.Base(path))
+	tmpFile, err := tempFile(fs, dir, prefix, "")
+	if err != nil {
+		return errors.Wrap(err, "can't create temporary file")
+	}
+	tmpPath := tmpFile.Name()
+	// only remove the temporary file if we fail before the rename
+	renamed := false
+	defer func() {
+		if !renamed {
+			fs.Remove(tmpPath)
+		}
+	}()
+
+	_, err = tmpFile.Write(data)
+	if err != nil {
+		tmpFile.Close()
+		return errors.Wrapf(err, "can't write %s", tmpPath)
+	}
+	err = tmpFile.Sync()
+	if err != nil {
+		tmpFile.Close()
+		return errors.Wrapf(err, "can't sync %s", tmpPath)
+	}
+	err = tmpFile.Close()
+	if err != nil {
+		return errors.Wrapf(err, "can't close %s", tmpPath)
+	}
+	err = fs.Chmod(tmpPath, perm)
+	if err != nil {
+		return errors.Wrapf(err, "can't set permissions on %s", tmpPath)
+	}
+	err = fs.Rename(tmpPath, path)
+	if err != nil {
+		return errors.Wrapf(err, "can't rename %s to %s", tmpPath, path)
+	}
+	renamed = true
+	return syncDir(fs, dir)
+}
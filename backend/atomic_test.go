@@ -0,0 +1,63 @@
+package backend
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func Test_writeFileAtomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "omw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fs := afero.NewOsFs()
+
+	t.Run("new file", func(t *testing.T) {
+		path := filepath.Join(dir, "new.toml")
+		if err := writeFileAtomic(fs, path, []byte("new"), 0644); err != nil {
+			t.Fatalf("writeFileAtomic() error = %v", err)
+		}
+		if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0644 {
+			t.Errorf("writeFileAtomic() mode = %v, %v, want 0644", info.Mode(), err)
+		}
+	})
+
+	t.Run("keeps mode", func(t *testing.T) {
+		path := filepath.Join(dir, "private.toml")
+		if err := ioutil.WriteFile(path, []byte("old"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := writeFileAtomic(fs, path, []byte("new"), 0644); err != nil {
+			t.Fatalf("writeFileAtomic() error = %v", err)
+		}
+		if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+			t.Errorf("writeFileAtomic() mode = %v, %v, want 0600", info.Mode(), err)
+		}
+	})
+
+	t.Run("follows symlink", func(t *testing.T) {
+		target := filepath.Join(dir, "dotfiles", "omw.toml")
+		os.MkdirAll(filepath.Dir(target), 0755)
+		if err := ioutil.WriteFile(target, []byte("old"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		link := filepath.Join(dir, "link.toml")
+		if err := os.Symlink(target, link); err != nil {
+			t.Skipf("can't create a symlink: %v", err)
+		}
+		if err := writeFileAtomic(fs, link, []byte("new"), 0644); err != nil {
+			t.Fatalf("writeFileAtomic() error = %v", err)
+		}
+		if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+			t.Errorf("writeFileAtomic() replaced the symlink: %v, %v", info.Mode(), err)
+		}
+		if data, err := ioutil.ReadFile(target); err != nil || string(data) != "new" {
+			t.Errorf("writeFileAtomic() target = %q, %v, want new", data, err)
+		}
+	})
+}
@@ -0,0 +1,166 @@
+package backend
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// KindTask is time spent working, counted in Report.TaskHrs
+	KindTask = "task"
+	// KindBreak is break time, counted in Report.BrkHrs
+	KindBreak = "break"
+	// KindIgnore is time to ignore, counted in Report.IgnoreHrs
+	KindIgnore = "ignore"
+)
+
+// Category describes a kind of entry, like a meeting or travel
+// An entry is in a category when its task ends with the category
+// marker or its category field is set to the category name.
+// Kind decides which of the report totals the category is added to,
+// every category also has its own total in Report.Totals.
+// ClassName and Color are used for FullCalendar events.  Account is the
+// ledger account of the category in timeclock exports.
+type Category struct {
+	Name      string `json:"name"`
+	Marker    string `json:"marker"`
+	Kind      string `json:"kind"`
+	ClassName string `json:"className,omitempty"`
+	Color     string `json:"color,omitempty"`
+	Account   string `json:"account,omitempty"`
+}
+
+// BuiltinCategories are always defined, but their marker, class name
+// and color can be changed by a category with the same name
+var BuiltinCategories = []Category{
+	{Name: "break", Marker: "**", Kind: KindBreak, ClassName: "breakEntry"},
+	{Name: "ignore", Marker: "***", Kind: KindIgnore, ClassName: "ignoreEntry"},
+}
+
+// ValidateCategories checks user-defined categories before they are
+// passed to WithCategories
+// Names and markers must be unique.  Markers can't contain whitespace or
+// start with the +, # or \ characters used by the task grammar.  The kind
+// of a built-in category can't be changed.
+func ValidateCategories(categories []Category) error {
+	for _, c := range categories {
+		for _, builtin := range BuiltinCategories {
+			if c.Name == builtin.Name && c.Kind != "" && c.Kind != builtin.Kind {
+				return errors.Errorf("category %q is built in and can't have kind %q", c.Name, c.Kind)
+			}
+		}
+	}
+	names := map[string]bool{}
+	markers := map[string]string{}
+	for _, c := range mergeCategories(categories) {
+		if c.Name == "" {
+			return errors.New("category is missing a name")
+		}
+		if c.Name == KindTask {
+			return errors.Errorf("category name %q is reserved", c.Name)
+		}
+		if names[c.Name] {
+			return errors.Errorf("category %q is defined more than once", c.Name)
+		}
+		names[c.Name] = true
+		if c.Marker == "" {
+			return errors.Errorf("category %q is missing a marker", c.Name)
+		}
+		if strings.IndexFunc(c.Marker, unicode.IsSpace) >= 0 || strings.ContainsAny(c.Marker[:1], `+#\`) {
+			return errors.Errorf("category %q has invalid marker %q", c.Name, c.Marker)
+		}
+		if other, exists := markers[c.Marker]; exists {
+			return errors.Errorf("categories %q and %q have the same marker %q", other, c.Name, c.Marker)
+		}
+		markers[c.Marker] = c.Name
+		switch c.Kind {
+		case KindTask, KindBreak, KindIgnore:
+		default:
+			return errors.Errorf("category %q has unknown kind %q - use task, break or ignore", c.Name, c.Kind)
+		}
+	}
+	return nil
+}
+
+// WithCategories adds user-defined categories to the built-in break and
+// ignore categories.  A category without a kind is counted as task time.
+// Check categories with ValidateCategories first.
+func WithCategories(categories ...Category) Option {
+	return func(b *Backend) {
+		b.config.categories = mergeCategories(categories)
+	}
+}
+
+// mergeCategories returns the built-in categories, overridden by any
+// category with the same name, followed by the other categories
+func mergeCategories(categories []Category) []Category {
+	merged := []Category{}
+	for _, builtin := range BuiltinCategories {
+		for _, c := range categories {
+			if c.Name == builtin.Name {
+				if c.Marker != "" {
+					builtin.Marker = c.Marker
+				}
+				if c.ClassName != "" {
+					builtin.ClassName = c.ClassName
+				}
+				builtin.Color = c.Color
+				builtin.Account = c.Account
+			}
+		}
+		merged = append(merged, builtin)
+	}
+	for _, c := range categories {
+		if isBuiltinCategory(c.Name) {
+			continue
+		}
+		if c.Kind == "" {
+			c.Kind = KindTask
+		}
+		if c.ClassName == "" {
+			c.ClassName = c.Name + "Entry"
+		}
+		merged = append(merged, c)
+	}
+	return merged
+}
+
+func isBuiltinCategory(name string) bool {
+	for _, c := range BuiltinCategories {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// category returns the category called name
+func (b *Backend) category(name string) (Category, bool) {
+	for _, c := range b.config.categories {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Category{}, false
+}
+
+// ignoreCategory returns the name of the category of ignored time
+func (b *Backend) ignoreCategory() string {
+	for _, c := range b.config.categories {
+		if c.Kind == KindIgnore {
+			return c.Name
+		}
+	}
+	return BuiltinCategories[1].Name
+}
+
+// markers maps the marker of every category to the category name
+func (b *Backend) markers() map[string]string {
+	markers := make(map[string]string, len(b.config.categories))
+	for _, c := range b.config.categories {
+		markers[c.Marker] = c.Name
+	}
+	return markers
+}
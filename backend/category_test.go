@@ -0,0 +1,32 @@
+package backend
+
+import "testing"
+
+func TestValidateCategories(t *testing.T) {
+	tests := []struct {
+		name       string
+		categories []Category
+		wantErr    bool
+	}{
+		{"none", nil, false},
+		{"meeting", []Category{{Name: "meeting", Marker: "!m"}}, false},
+		{"override builtin", []Category{{Name: "break", Marker: "!b", Color: "green"}}, false},
+		{"override builtin with its kind", []Category{{Name: "ignore", Kind: KindIgnore, Color: "grey"}}, false},
+		{"override builtin kind", []Category{{Name: "break", Kind: KindTask}}, true},
+		{"missing name", []Category{{Marker: "!m"}}, true},
+		{"reserved name", []Category{{Name: "task", Marker: "!t"}}, true},
+		{"missing marker", []Category{{Name: "meeting"}}, true},
+		{"duplicate name", []Category{{Name: "a", Marker: "!a"}, {Name: "a", Marker: "!b"}}, true},
+		{"duplicate marker", []Category{{Name: "travel", Marker: "**"}}, true},
+		{"marker with space", []Category{{Name: "a", Marker: "! a"}}, true},
+		{"marker like a tag", []Category{{Name: "a", Marker: "#a"}}, true},
+		{"unknown kind", []Category{{Name: "a", Marker: "!a", Kind: "work"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateCategories(tt.categories); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCategories() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
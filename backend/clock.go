@@ -0,0 +1,28 @@
+package backend
+
+import "time"
+
+// Clock tells a Backend the current time, which is the end time of new
+// entries and the "now" that report ranges like today are relative to
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is the default Clock
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// WithClock replaces the system clock, ie: with a fixed time in tests
+func WithClock(c Clock) Option {
+	return func(b *Backend) {
+		b.clock = c
+	}
+}
+
+// now returns the current time without a monotonic clock reading
+func (b *Backend) now() time.Time {
+	return b.clock.Now().Round(0)
+}
@@ -0,0 +1,84 @@
+package backend
+
+import (
+	"strings"
+	"time"
+)
+
+// helloTask is the task added by Hello at the start of a work day
+const helloTask = "hello"
+
+// maxTaskLength is how far before the start of a report to look for the
+// entry that a task in the report started after
+const maxTaskLength = 24 * time.Hour
+
+// WithDayStartHello starts a new day in reports only at a hello entry,
+// instead of at the day start time.  Tasks that run past the day start
+// time are split between the two days.
+func WithDayStartHello(enabled bool) Option {
+	return func(b *Backend) {
+		b.config.dayStartHello = enabled
+	}
+}
+
+// dayOf returns midnight of the day that t counts towards, which is the
+// day before if t is earlier than the day start time
+func (b *Backend) dayOf(t time.Time) time.Time {
+	shifted := t.Add(-b.config.dayStart)
+	return time.Date(shifted.Year(), shifted.Month(), shifted.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// dayBoundary returns the time that day starts at
+func (b *Backend) dayBoundary(day time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location()).Add(b.config.dayStart)
+}
+
+// nextDay returns midnight of the day after day
+func nextDay(day time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, day.Location())
+}
+
+// startsDay returns true if entry, which ends after previous, is the
+// first entry of a day and so has no duration
+// A hello entry always starts a day.  Without WithDayStartHello, so does
+// the first entry after the day start, and an entry only continues from
+// previous on the same day.  With it, any other entry continues from
+// previous and is split by splitEntry if it runs past the day start.
+func (b *Backend) startsDay(entry *ReportEntry, previous time.Time) bool {
+	if strings.EqualFold(entry.Title, helloTask) {
+		return true
+	}
+	if b.config.dayStartHello {
+		return false
+	}
+	return b.dayOf(entry.Ts).After(b.dayOf(previous))
+}
+
+// splitEntry splits entry at every day start between its start and end
+// time, so each part counts towards its own day.  The parts keep the ID
+// of the entry and are numbered by Part.
+func (b *Backend) splitEntry(entry ReportEntry) []ReportEntry {
+	if !entry.Ts.After(entry.Start) {
+		entry.Day = b.dayOf(entry.Ts)
+		return []ReportEntry{entry}
+	}
+	parts := []ReportEntry{}
+	day := b.dayOf(entry.Start)
+	for next := b.dayBoundary(nextDay(day)); next.Before(entry.Ts); next = b.dayBoundary(nextDay(day)) {
+		part := entry
+		part.Day = day
+		part.End = next
+		part.Ts = next
+		part.Duration = next.Sub(part.Start)
+		part.Part = len(parts) + 1
+		parts = append(parts, part)
+		entry.Start = next
+		day = nextDay(day)
+	}
+	entry.Day = day
+	entry.Duration = entry.Ts.Sub(entry.Start)
+	if len(parts) > 0 {
+		entry.Part = len(parts) + 1
+	}
+	return append(parts, entry)
+}
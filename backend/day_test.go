@@ -0,0 +1,148 @@
+package backend
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newTestBackend returns a backend with a TOML timesheet of entries
+// in a temporary directory, which is removed by the returned function
+func newTestBackend(t *testing.T, entries []SavedEntry, opts ...Option) (*Backend, func()) {
+	dir, err := ioutil.TempDir("", "omw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "omw.toml")
+	ioutil.WriteFile(path, nil, 0644)
+	b := Create(nil, dir, path, opts...)
+	if len(entries) > 0 {
+		if err := b.store.Append(entries...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return b, func() { os.RemoveAll(dir) }
+}
+
+func TestBackend_Report_dayStart(t *testing.T) {
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.Local)
+	entries := []SavedEntry{
+		{ID: "a", End: day.Add(20 * time.Hour), Task: "hello"},
+		{ID: "b", End: day.Add(26 * time.Hour), Task: "night shift"},
+		{ID: "c", End: day.Add(29 * time.Hour), Task: "wrap up"},
+	}
+	type part struct {
+		ID       string
+		Day      int
+		Duration time.Duration
+	}
+	tests := []struct {
+		name     string
+		opts     []Option
+		from, to string
+		want     []part
+	}{
+		{"midnight", nil, "2020-1-2", "2020-1-3",
+			[]part{{"a", 2, 0}, {"b", 3, 0}, {"c", 3, 3 * time.Hour}}},
+		{"4am", []Option{WithDayStart(4 * time.Hour)}, "2020-1-2", "2020-1-3",
+			[]part{{"a", 2, 0}, {"b", 2, 6 * time.Hour}, {"c", 3, 0}}},
+		{"midnight first day", nil, "2020-1-2", "2020-1-2",
+			[]part{{"a", 2, 0}}},
+		{"4am first day", []Option{WithDayStart(4 * time.Hour)}, "2020-1-2", "2020-1-2",
+			[]part{{"a", 2, 0}, {"b", 2, 6 * time.Hour}}},
+		{"hello", []Option{WithDayStart(4 * time.Hour), WithDayStartHello(true)}, "2020-1-2", "2020-1-3",
+			[]part{{"a", 2, 0}, {"b", 2, 6 * time.Hour}, {"c", 2, 2 * time.Hour}, {"c", 3, time.Hour}}},
+		{"hello second day", []Option{WithDayStart(4 * time.Hour), WithDayStartHello(true)}, "2020-1-3", "2020-1-3",
+			[]part{{"c", 3, time.Hour}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, cleanup := newTestBackend(t, entries, tt.opts...)
+			defer cleanup()
+			report, err := b.BuildReport(context.Background(), tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("BuildReport() error = %v", err)
+			}
+			got := []part{}
+			var total time.Duration
+			for _, e := range report.Entries {
+				got = append(got, part{e.ID, e.Day.Day(), e.Duration})
+				total += e.Duration
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BuildReport() entries = %v, want %v", got, tt.want)
+			}
+			if report.TaskHrs != total {
+				t.Errorf("BuildReport() TaskHrs = %v, want %v", report.TaskHrs, total)
+			}
+		})
+	}
+}
+
+func TestBackend_Report_dayGap(t *testing.T) {
+	friday := time.Date(2020, 1, 3, 0, 0, 0, 0, time.Local)
+	entries := []SavedEntry{
+		{ID: "a", End: friday.Add(9 * time.Hour), Task: "hello"},
+		{ID: "b", End: friday.Add(17 * time.Hour), Task: "review"},
+		{ID: "c", End: friday.AddDate(0, 0, 3).Add(10 * time.Hour), Task: "standup"},
+	}
+	tests := []struct {
+		name string
+		opts []Option
+		want time.Duration
+	}{
+		{"day start", nil, 8 * time.Hour},
+		{"4am", []Option{WithDayStart(4 * time.Hour)}, 8 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, cleanup := newTestBackend(t, entries, tt.opts...)
+			defer cleanup()
+			report, err := b.BuildReport(context.Background(), "2020-1-3", "2020-1-6")
+			if err != nil {
+				t.Fatalf("BuildReport() error = %v", err)
+			}
+			if report.TaskHrs != tt.want {
+				t.Errorf("BuildReport() TaskHrs = %v, want %v", report.TaskHrs, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackend_Report_consecutiveDays(t *testing.T) {
+	monday := time.Date(2020, 1, 6, 0, 0, 0, 0, time.Local)
+	entries := []SavedEntry{
+		{ID: "a", End: monday.Add(9 * time.Hour), Task: "email"},
+		{ID: "b", End: monday.Add(17 * time.Hour), Task: "review"},
+		{ID: "c", End: monday.AddDate(0, 0, 1).Add(9*time.Hour + 30*time.Minute), Task: "standup"},
+		{ID: "d", End: monday.AddDate(0, 0, 1).Add(12 * time.Hour), Task: "code"},
+	}
+	tests := []struct {
+		name string
+		opts []Option
+		day  string
+		want time.Duration
+	}{
+		{"monday", nil, "2020-1-6", 8 * time.Hour},
+		{"tuesday", nil, "2020-1-7", 2*time.Hour + 30*time.Minute},
+		{"4am monday", []Option{WithDayStart(4 * time.Hour)}, "2020-1-6", 8 * time.Hour},
+		{"4am tuesday", []Option{WithDayStart(4 * time.Hour)}, "2020-1-7", 2*time.Hour + 30*time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, cleanup := newTestBackend(t, entries, tt.opts...)
+			defer cleanup()
+			report, err := b.BuildReport(context.Background(), tt.day, tt.day)
+			if err != nil {
+				t.Fatalf("BuildReport() error = %v", err)
+			}
+			if report.TaskHrs != tt.want {
+				t.Errorf("BuildReport() TaskHrs = %v, want %v", report.TaskHrs, tt.want)
+			}
+		})
+	}
+}
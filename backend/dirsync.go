@@ -0,0 +1,23 @@
+//go:build !windows
+// +build !windows
+
+package backend
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+// syncDir flushes directory entries, like a rename, to disk
+func syncDir(fs afero.Fs, dir string) error {
+	d, err := fs.Open(dir)
+	if err != nil {
+		return errors.Wrapf(err, "can't open directory %s", dir)
+	}
+	defer d.Close()
+	err = d.Sync()
+	if err != nil {
+		return errors.Wrapf(err, "can't sync directory %s", dir)
+	}
+	return nil
+}
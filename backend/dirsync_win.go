@@ -0,0 +1,12 @@
+//go:build windows
+// +build windows
+
+package backend
+
+import "github.com/spf13/afero"
+
+// syncDir is a no-op on Windows, which can't open a directory for
+// syncing - NTFS journals the rename itself
+func syncDir(fs afero.Fs, dir string) error {
+	return nil
+}
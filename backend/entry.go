@@ -0,0 +1,90 @@
+package backend
+
+import "time"
+
+// EntryOption sets optional fields on a new entry in Add, or changes
+// a saved entry in AmendEntry
+type EntryOption func(e *SavedEntry)
+
+// EntryProject sets the project of a new entry
+func EntryProject(project string) EntryOption {
+	return func(e *SavedEntry) {
+		e.Project = project
+	}
+}
+
+// EntryTags adds tags to a new entry
+func EntryTags(tags ...string) EntryOption {
+	return func(e *SavedEntry) {
+		e.Tags = appendTags(e.Tags, tags...)
+	}
+}
+
+// EntryNotes sets the notes of a new entry
+func EntryNotes(notes string) EntryOption {
+	return func(e *SavedEntry) {
+		e.Notes = notes
+	}
+}
+
+// EntryBillable marks a new entry as billable
+func EntryBillable(billable bool) EntryOption {
+	return func(e *SavedEntry) {
+		e.Billable = billable
+	}
+}
+
+// EntryCategory sets the category of a new entry
+func EntryCategory(category string) EntryOption {
+	return func(e *SavedEntry) {
+		e.Category = category
+	}
+}
+
+// EntryUntag removes tags from an entry, see AmendEntry
+func EntryUntag(tags ...string) EntryOption {
+	return func(e *SavedEntry) {
+		var kept []string
+		for _, t := range e.Tags {
+			if !containsTag(tags, t) {
+				kept = append(kept, t)
+			}
+		}
+		e.Tags = kept
+	}
+}
+
+// EntryAt sets the end time of a new entry, instead of the current time
+// An entry earlier than the last entry is inserted in chronological order.
+func EntryAt(t time.Time) EntryOption {
+	return func(e *SavedEntry) {
+		e.End = t
+	}
+}
+
+// entryTask replaces the task of an entry, see RetitleEntry
+func entryTask(task string) EntryOption {
+	return func(e *SavedEntry) {
+		e.Task = task
+	}
+}
+
+// appendTags adds tags that are not already in existing
+func appendTags(existing []string, tags ...string) []string {
+	for _, tag := range tags {
+		if !containsTag(existing, tag) && tag != "" {
+			existing = append(existing, tag)
+		}
+	}
+	return existing
+}
+
+// containsTag returns true if tag is one of tags
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
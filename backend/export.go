@@ -0,0 +1,110 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Exporter writes the entries of a report in the format of another
+// program, see RegisterExporter
+type Exporter interface {
+	Export(w io.Writer, r *Report, opts ExportOptions) error
+}
+
+// ExporterFunc is an Exporter function
+type ExporterFunc func(w io.Writer, r *Report, opts ExportOptions) error
+
+// Export calls f
+func (f ExporterFunc) Export(w io.Writer, r *Report, opts ExportOptions) error {
+	return f(w, r, opts)
+}
+
+// ExportOptions configure an Exporter
+type ExportOptions struct {
+	// Now is the time of the export, the default is the time of the Backend clock
+	Now time.Time
+	// Categories and Account are the ledger accounts of entries, see
+	// WithCategories and WithAccount
+	Categories []Category
+	Account    string
+	// JiraProjects are the only project keys of Jira issue keys, see
+	// WithJiraProjects
+	JiraProjects []string
+}
+
+var exporters = map[string]Exporter{}
+
+// RegisterExporter makes an Exporter available to Export by name
+func RegisterExporter(name string, exp Exporter) {
+	exporters[strings.ToLower(name)] = exp
+}
+
+// ExporterNames returns the names of the registered exporters
+func ExporterNames() []string {
+	names := make([]string, 0, len(exporters))
+	for name := range exporters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	RegisterExporter("csv", ExporterFunc(exportCSV))
+	RegisterExporter("ics", ExporterFunc(exportICS))
+	RegisterExporter("timeclock", ExporterFunc(exportTimeclock))
+	RegisterExporter("org", ExporterFunc(exportOrg))
+	RegisterExporter("jira", ExporterFunc(exportJira))
+	RegisterExporter("jira-csv", ExporterFunc(exportJiraCSV))
+}
+
+// Export writes the entries from start to end to w with the exporter
+// named format.  The range and opts are the same as for BuildReport.
+func (b *Backend) Export(ctx context.Context, format, start, end string, w io.Writer, opts ...ReportOption) error {
+	exp, ok := exporters[strings.ToLower(format)]
+	if !ok {
+		return errors.Errorf("unknown export format %q - use %s", format, strings.Join(ExporterNames(), ", "))
+	}
+	report, err := b.BuildReport(ctx, start, end, opts...)
+	if err != nil {
+		return err
+	}
+	err = exp.Export(w, report, ExportOptions{
+		Now:          b.now(),
+		Categories:   b.config.categories,
+		Account:      b.config.account,
+		JiraProjects: b.config.jiraProjects,
+	})
+	return errors.Wrapf(err, "can't export %s", format)
+}
+
+// exportedEntries returns the entries of r with a duration
+// Entries without one, like hello, only mark the start of a day.
+func exportedEntries(r *Report) []ReportEntry {
+	entries := []ReportEntry{}
+	for _, e := range r.Entries {
+		if e.Duration > 0 {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// exportID identifies an exported entry, the parts of an entry that is
+// split at the start of a day get the number of the part after its ID
+func exportID(e ReportEntry) string {
+	id := e.ID
+	if id == "" {
+		id = e.Ts.UTC().Format("20060102T150405Z")
+	}
+	if e.Part > 0 {
+		return fmt.Sprintf("%s-%d", id, e.Part)
+	}
+	return id
+}
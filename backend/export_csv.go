@@ -0,0 +1,40 @@
+package backend
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// exportTimeLayout is the layout of the start and end columns, which
+// spreadsheets read as a local date and time
+const exportTimeLayout = "2006-01-02 15:04:05"
+
+// exportCSV writes a row for every entry with a duration, in decimal
+// hours like the timesheet grid.  The columns are named like the fields
+// of the csv importer, so the file can be imported again.
+func exportCSV(output io.Writer, r *Report, opts ExportOptions) error {
+	w := csv.NewWriter(output)
+	w.Write([]string{CSVID, CSVStart, CSVEnd, CSVDuration, CSVTask, CSVCategory, CSVProject, CSVTags, CSVNotes, CSVBillable})
+	for _, e := range exportedEntries(r) {
+		billable := ""
+		if e.Billable {
+			billable = "yes"
+		}
+		w.Write([]string{
+			exportID(e),
+			e.Start.Format(exportTimeLayout),
+			e.Start.Add(e.Duration).Format(exportTimeLayout),
+			fmt.Sprintf("%.2f", decimalHours(e.Duration)),
+			e.Title,
+			e.Category,
+			e.Project,
+			strings.Join(e.Tags, ", "),
+			e.Notes,
+			billable,
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
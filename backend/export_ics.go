@@ -0,0 +1,81 @@
+package backend
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// icsTimeLayout is the iCalendar UTC date-time format
+const icsTimeLayout = "20060102T150405Z"
+
+// icsLineLength is the number of octets a content line is folded at
+const icsLineLength = 75
+
+// exportICS writes an iCalendar (RFC 5545) VEVENT for every entry with a
+// duration.  The UID is the entry ID, so calendar apps update the events
+// of entries that were exported before instead of adding them again.
+func exportICS(output io.Writer, r *Report, opts ExportOptions) error {
+	w := bufio.NewWriter(output)
+	line := func(name, value string) {
+		writeICSLine(w, name+":"+value)
+	}
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//mcdafydd//omw//EN")
+	line("CALSCALE", "GREGORIAN")
+	for _, e := range exportedEntries(r) {
+		line("BEGIN", "VEVENT")
+		line("UID", icsText(exportID(e)))
+		line("DTSTAMP", icsTime(opts.Now))
+		line("DTSTART", icsTime(e.Start))
+		line("DTEND", icsTime(e.Start.Add(e.Duration)))
+		line("SUMMARY", icsText(e.Title))
+		if e.Notes != "" {
+			line("DESCRIPTION", icsText(e.Notes))
+		}
+		categories := []string{}
+		for _, c := range append([]string{e.Category, e.Project}, e.Tags...) {
+			if c != "" {
+				categories = append(categories, icsText(c))
+			}
+		}
+		if len(categories) > 0 {
+			line("CATEGORIES", strings.Join(categories, ","))
+		}
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+	return w.Flush()
+}
+
+// icsTime formats t in UTC
+func icsTime(t time.Time) string {
+	return t.UTC().Format(icsTimeLayout)
+}
+
+// icsText escapes a TEXT value
+func icsText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// writeICSLine writes a content line ending in CRLF, folded so that no
+// line is longer than icsLineLength octets without splitting a character
+func writeICSLine(w *bufio.Writer, s string) {
+	limit := icsLineLength
+	for len(s) > limit {
+		n := limit
+		for n > 0 && !utf8.RuneStart(s[n]) {
+			n--
+		}
+		w.WriteString(s[:n])
+		w.WriteString("\r\n ")
+		s = s[n:]
+		// the space that starts a folded line counts towards its length
+		limit = icsLineLength - 1
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
@@ -0,0 +1,150 @@
+package backend
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// jiraIssueKey matches a Jira issue key in a task title, ie: ABC-123
+// The project key starts with two letters and has at most 10 characters,
+// and issue numbers don't start with 0.
+var jiraIssueKey = regexp.MustCompile(`\b([A-Z]{2}[A-Z0-9_]{0,8})-[1-9][0-9]*\b`)
+
+// notJiraProjects are abbreviations of standards and algorithms that look
+// like issue keys, ie: UTF-8 or SHA-256.  They are only matched when they
+// are listed in WithJiraProjects.
+var notJiraProjects = map[string]bool{
+	"AES": true, "CVE": true, "HTTP": true, "IEC": true, "IEEE": true,
+	"ISO": true, "MD": true, "RFC": true, "SHA": true, "TLS": true, "UTF": true,
+}
+
+// jiraTimeLayout is the format of the started time of a Jira worklog
+const jiraTimeLayout = "2006-01-02T15:04:05.000-0700"
+
+// jiraWorklog is the time spent on an issue on one day, or on a task
+// without an issue key
+type jiraWorklog struct {
+	IssueKey         string `json:"issueKey,omitempty"`
+	Started          string `json:"started"`
+	TimeSpentSeconds int64  `json:"timeSpentSeconds"`
+	Comment          string `json:"comment"`
+}
+
+// jiraExport is the JSON document written by exportJira
+type jiraExport struct {
+	Worklogs  []jiraWorklog `json:"worklogs"`
+	Unmatched []jiraWorklog `json:"unmatched"`
+}
+
+// exportJira writes the Jira worklogs of a report as JSON, with the tasks
+// without an issue key listed separately as unmatched
+func exportJira(w io.Writer, r *Report, opts ExportOptions) error {
+	worklogs, unmatched := jiraWorklogs(r, opts.JiraProjects)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(jiraExport{Worklogs: worklogs, Unmatched: unmatched})
+}
+
+// exportJiraCSV writes the Jira worklogs of a report as CSV, followed by
+// the tasks without an issue key, which have an empty issueKey column
+func exportJiraCSV(output io.Writer, r *Report, opts ExportOptions) error {
+	worklogs, unmatched := jiraWorklogs(r, opts.JiraProjects)
+	w := csv.NewWriter(output)
+	w.Write([]string{"issueKey", "started", "timeSpentSeconds", "comment"})
+	for _, wl := range append(worklogs, unmatched...) {
+		w.Write([]string{wl.IssueKey, wl.Started, strconv.FormatInt(wl.TimeSpentSeconds, 10), wl.Comment})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// jiraWorklogs adds up the time spent on each issue on each day of a
+// report, from the first issue key in the title of every entry, see
+// jiraKey.  Entries without an issue key are added up by title instead,
+// and breaks and ignored entries aren't logged as work.  A worklog starts
+// when its first entry started, its comment lists the titles of its
+// entries and its time is rounded to the minute, because Jira doesn't log
+// less than a minute.
+func jiraWorklogs(r *Report, projects []string) (worklogs, unmatched []jiraWorklog) {
+	type group struct {
+		key     string
+		started time.Time
+		spent   time.Duration
+		titles  []string
+		seen    map[string]bool
+	}
+	keys := map[string]bool{}
+	for _, project := range projects {
+		keys[strings.ToUpper(project)] = true
+	}
+	groups := map[string]*group{}
+	order := []string{}
+	for _, e := range exportedEntries(r) {
+		if e.Brk || e.Ignore {
+			continue
+		}
+		key := jiraKey(e.Title, keys)
+		id := e.Day.Format("2006-01-02") + " " + key
+		if key == "" {
+			id += "\x00" + e.Title
+		}
+		g, ok := groups[id]
+		if !ok {
+			g = &group{key: key, started: e.Start, seen: map[string]bool{}}
+			groups[id] = g
+			order = append(order, id)
+		}
+		g.spent += e.Duration
+		if !g.seen[e.Title] {
+			g.seen[e.Title] = true
+			g.titles = append(g.titles, e.Title)
+		}
+	}
+
+	worklogs, unmatched = []jiraWorklog{}, []jiraWorklog{}
+	for _, id := range order {
+		g := groups[id]
+		spent := g.spent.Round(time.Minute)
+		if spent < time.Minute {
+			spent = time.Minute
+		}
+		wl := jiraWorklog{
+			IssueKey:         g.key,
+			Started:          g.started.Format(jiraTimeLayout),
+			TimeSpentSeconds: int64(spent / time.Second),
+			Comment:          strings.Join(g.titles, "; "),
+		}
+		if g.key == "" {
+			unmatched = append(unmatched, wl)
+			continue
+		}
+		worklogs = append(worklogs, wl)
+	}
+	return worklogs, unmatched
+}
+
+// jiraKey returns the first issue key in title, or an empty string
+// Only the keys of projects match, or any key but notJiraProjects if
+// projects is empty.  A key followed by another number, like the year in
+// CVE-2020-1234 or a version like ABC-1.2, is not an issue key.
+func jiraKey(title string, projects map[string]bool) string {
+	for _, m := range jiraIssueKey.FindAllStringSubmatchIndex(title, -1) {
+		project := title[m[2]:m[3]]
+		if rest := title[m[1]:]; len(rest) > 1 && (rest[0] == '-' || rest[0] == '.') && '0' <= rest[1] && rest[1] <= '9' {
+			continue
+		}
+		if len(projects) == 0 && notJiraProjects[project] {
+			continue
+		}
+		if len(projects) > 0 && !projects[project] {
+			continue
+		}
+		return title[m[0]:m[1]]
+	}
+	return ""
+}
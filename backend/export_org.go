@@ -0,0 +1,96 @@
+package backend
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// orgTimeLayout is an inactive org-mode timestamp without the brackets
+const orgTimeLayout = "2006-01-02 Mon 15:04"
+
+// orgHeading is a task in an org-mode export
+type orgHeading struct {
+	title  string
+	tags   []string
+	clocks []ReportEntry
+}
+
+// exportOrg writes an org-mode outline with a heading for every project
+// and a heading below it for every task, or a top-level heading for tasks
+// without a project.  Each task has a CLOCK line for each of its entries,
+// newest first like org-mode adds them, in a LOGBOOK drawer.  Breaks and
+// ignored entries get no CLOCK line, so org clock tables only add up work.
+func exportOrg(output io.Writer, r *Report, opts ExportOptions) error {
+	projects := map[string][]*orgHeading{}
+	for _, e := range exportedEntries(r) {
+		if e.Brk || e.Ignore {
+			continue
+		}
+		var h *orgHeading
+		for _, heading := range projects[e.Project] {
+			if heading.title == e.Title {
+				h = heading
+			}
+		}
+		if h == nil {
+			h = &orgHeading{title: e.Title}
+			projects[e.Project] = append(projects[e.Project], h)
+		}
+		for _, tag := range e.Tags {
+			h.tags = appendTags(h.tags, orgTag(tag))
+		}
+		h.clocks = append(h.clocks, e)
+	}
+	names := make([]string, 0, len(projects))
+	for name := range projects {
+		names = append(names, name)
+	}
+	// tasks without a project come first
+	sort.Strings(names)
+
+	w := bufio.NewWriter(output)
+	for _, name := range names {
+		stars := "*"
+		if name != "" {
+			fmt.Fprintf(w, "* %s\n", name)
+			stars = "**"
+		}
+		for _, h := range projects[name] {
+			fmt.Fprintf(w, "%s %s", stars, h.title)
+			if len(h.tags) > 0 {
+				fmt.Fprintf(w, " :%s:", strings.Join(h.tags, ":"))
+			}
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, ":LOGBOOK:")
+			for i := len(h.clocks) - 1; i >= 0; i-- {
+				fmt.Fprintln(w, orgClock(h.clocks[i]))
+			}
+			fmt.Fprintln(w, ":END:")
+		}
+	}
+	return w.Flush()
+}
+
+// orgClock returns the CLOCK line of e
+func orgClock(e ReportEntry) string {
+	start := e.Start.Truncate(time.Minute)
+	end := e.Start.Add(e.Duration).Truncate(time.Minute)
+	minutes := int(end.Sub(start) / time.Minute)
+	return fmt.Sprintf("CLOCK: [%s]--[%s] => %2d:%02d",
+		start.Format(orgTimeLayout), end.Format(orgTimeLayout), minutes/60, minutes%60)
+}
+
+// orgTag replaces the characters that org-mode doesn't allow in tags
+func orgTag(tag string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_@#%", r) {
+			return r
+		}
+		return '_'
+	}, tag)
+}
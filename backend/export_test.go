@@ -0,0 +1,232 @@
+package backend
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackend_Export(t *testing.T) {
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	entries := []SavedEntry{
+		{ID: "a", End: day.Add(20 * time.Hour), Task: "hello"},
+		{ID: "b", End: day.Add(26 * time.Hour), Task: "night shift", Project: "ops", Tags: []string{"oncall"}, Notes: "paged, twice"},
+		{ID: "c", End: day.Add(29 * time.Hour), Task: "wrap up **"},
+	}
+	now := time.Date(2020, 2, 1, 12, 0, 0, 0, time.UTC)
+	b, cleanup := newTestBackend(t, entries, WithDayStart(4*time.Hour), WithDayStartHello(true), WithClock(&testClock{now: now}))
+	defer cleanup()
+
+	tests := []struct {
+		name    string
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{"csv", "csv", `id,start,end,duration,task,category,project,tags,notes,billable
+b,2020-01-02 20:00:00,2020-01-03 02:00:00,6.00,night shift,,ops,oncall,"paged, twice",
+c-1,2020-01-03 02:00:00,2020-01-03 04:00:00,2.00,wrap up,break,,,,
+c-2,2020-01-03 04:00:00,2020-01-03 05:00:00,1.00,wrap up,break,,,,
+`, false},
+		{"ics", "ics", `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//mcdafydd//omw//EN
+CALSCALE:GREGORIAN
+BEGIN:VEVENT
+UID:b
+DTSTAMP:20200201T120000Z
+DTSTART:20200102T200000Z
+DTEND:20200103T020000Z
+SUMMARY:night shift
+DESCRIPTION:paged\, twice
+CATEGORIES:ops,oncall
+END:VEVENT
+BEGIN:VEVENT
+UID:c-1
+DTSTAMP:20200201T120000Z
+DTSTART:20200103T020000Z
+DTEND:20200103T040000Z
+SUMMARY:wrap up
+CATEGORIES:break
+END:VEVENT
+BEGIN:VEVENT
+UID:c-2
+DTSTAMP:20200201T120000Z
+DTSTART:20200103T040000Z
+DTEND:20200103T050000Z
+SUMMARY:wrap up
+CATEGORIES:break
+END:VEVENT
+END:VCALENDAR
+`, false},
+		{"timeclock", "timeclock", `; id:b, tags:oncall
+; notes:"paged, twice"
+i 2020/01/02 20:00:00 ops  night shift
+o 2020/01/03 02:00:00
+`, false},
+		{"org", "org", `* ops
+** night shift :oncall:
+:LOGBOOK:
+CLOCK: [2020-01-02 Thu 20:00]--[2020-01-03 Fri 02:00] =>  6:00
+:END:
+`, false},
+		{"unknown", "xml", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := b.Export(context.Background(), tt.format, "2020-01-02", "2020-01-03", &buf)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Export() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			got := strings.Replace(buf.String(), "\r\n", "\n", -1)
+			if got != tt.want {
+				t.Errorf("Export() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_writeICSLine(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+	}{
+		{"short", "SUMMARY:review"},
+		{"ascii", "SUMMARY:" + strings.Repeat("a", 200)},
+		{"utf-8", "SUMMARY:" + strings.Repeat("ä", 100)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := bufio.NewWriter(&buf)
+			writeICSLine(w, tt.s)
+			w.Flush()
+			lines := strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n")
+			unfolded := ""
+			for i, line := range lines {
+				if len(line) > icsLineLength {
+					t.Errorf("writeICSLine() line %d is %d octets long", i, len(line))
+				}
+				if i > 0 {
+					line = strings.TrimPrefix(line, " ")
+				}
+				unfolded += line
+			}
+			if unfolded != tt.s {
+				t.Errorf("writeICSLine() unfolded = %q, want %q", unfolded, tt.s)
+			}
+		})
+	}
+}
+
+func TestBackend_Export_jira(t *testing.T) {
+	day := time.Date(2020, 1, 6, 0, 0, 0, 0, time.UTC)
+	entries := []SavedEntry{
+		{ID: "a", End: day.Add(9 * time.Hour), Task: "hello"},
+		{ID: "b", End: day.Add(10 * time.Hour), Task: "ABC-123 fix login"},
+		{ID: "c", End: day.Add(10*time.Hour + 30*time.Minute), Task: "standup"},
+		{ID: "d", End: day.Add(11*time.Hour + 15*time.Minute + 20*time.Second), Task: "review ABC-123"},
+		{ID: "e", End: day.Add(12 * time.Hour), Task: "lunch **"},
+		{ID: "f", End: day.Add(13 * time.Hour), Task: "deploy abc-9", Project: "OPS-7"},
+		{ID: "g", End: day.Add(33 * time.Hour), Task: "hello"},
+		{ID: "h", End: day.Add(34 * time.Hour), Task: "ABC-123 fix login"},
+	}
+	b, cleanup := newTestBackend(t, entries)
+	defer cleanup()
+
+	tests := []struct {
+		name   string
+		format string
+		want   string
+	}{
+		{"json", "jira", `{
+  "worklogs": [
+    {
+      "issueKey": "ABC-123",
+      "started": "2020-01-06T09:00:00.000+0000",
+      "timeSpentSeconds": 6300,
+      "comment": "ABC-123 fix login; review ABC-123"
+    },
+    {
+      "issueKey": "ABC-123",
+      "started": "2020-01-07T09:00:00.000+0000",
+      "timeSpentSeconds": 3600,
+      "comment": "ABC-123 fix login"
+    }
+  ],
+  "unmatched": [
+    {
+      "started": "2020-01-06T10:00:00.000+0000",
+      "timeSpentSeconds": 1800,
+      "comment": "standup"
+    },
+    {
+      "started": "2020-01-06T12:00:00.000+0000",
+      "timeSpentSeconds": 3600,
+      "comment": "deploy abc-9"
+    }
+  ]
+}
+`},
+		{"csv", "jira-csv", `issueKey,started,timeSpentSeconds,comment
+ABC-123,2020-01-06T09:00:00.000+0000,6300,ABC-123 fix login; review ABC-123
+ABC-123,2020-01-07T09:00:00.000+0000,3600,ABC-123 fix login
+,2020-01-06T10:00:00.000+0000,1800,standup
+,2020-01-06T12:00:00.000+0000,3600,deploy abc-9
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := b.Export(context.Background(), tt.format, "2020-01-06", "2020-01-07", &buf)
+			if err != nil {
+				t.Fatalf("Export() error = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("Export() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_jiraKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		title    string
+		projects []string
+		want     string
+	}{
+		{"key", "ABC-123 fix login", nil, "ABC-123"},
+		{"second word", "review ABC-123", nil, "ABC-123"},
+		{"digits and underscore", "deploy OPS2_WEB-7", nil, "OPS2_WEB-7"},
+		{"first key", "ABC-1 blocks XYZ-2", nil, "ABC-1"},
+		{"encoding", "fix UTF-8 decoding", nil, ""},
+		{"date format", "parse ISO-8601 dates", nil, ""},
+		{"hash", "check SHA-256 sums", nil, ""},
+		{"cve", "patch CVE-2020-1234", nil, ""},
+		{"version", "release ABC-1.2", nil, ""},
+		{"one letter", "plan A-1", nil, ""},
+		{"letter and digit", "room B2-3", nil, ""},
+		{"leading zero", "ABC-0123", nil, ""},
+		{"lower case", "abc-123", nil, ""},
+		{"abbreviation after key", "UTF-8 bug in ABC-9", nil, "ABC-9"},
+		{"allowed project", "OPS-7 and ABC-123", []string{"ABC"}, "ABC-123"},
+		{"not an allowed project", "OPS-7 deploy", []string{"ABC"}, ""},
+		{"allowed abbreviation", "RFC-42 draft", []string{"RFC"}, "RFC-42"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			projects := map[string]bool{}
+			for _, p := range tt.projects {
+				projects[p] = true
+			}
+			if got := jiraKey(tt.title, projects); got != tt.want {
+				t.Errorf("jiraKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
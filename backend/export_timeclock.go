@@ -0,0 +1,101 @@
+package backend
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultAccount is the ledger account of entries without a project in
+// timeclock exports
+const DefaultAccount = "omw"
+
+// timeclockLayout is the date and time of a timeclock clock-in or
+// clock-out line
+const timeclockLayout = "2006/01/02 15:04:05"
+
+// Tags in the comment before a clock-in, in the name:value format of
+// hledger tags
+const (
+	timeclockID       = "id"
+	timeclockPart     = "part"
+	timeclockCategory = "category"
+	timeclockTags     = "tags"
+	timeclockBillable = "billable"
+	timeclockNotes    = "notes"
+)
+
+// timeclockSemicolon matches a semicolon that would start a comment in
+// the description of a clock-in
+var timeclockSemicolon = regexp.MustCompile(`(\s);`)
+
+// exportTimeclock writes a clock-in and a clock-out line for every entry
+// with a duration, in the timeclock format of hledger and ledger.  The
+// account is the account of the category of the entry, or else its
+// project or opts.Account.  A break or ignored entry is only clocked when
+// its category has an account, see timeclockAccount.  A comment before
+// each clock-in keeps the ID and the fields that aren't in the account or
+// description, with the notes quoted on a line of their own, so the file
+// can be imported again.  A semicolon after a space in the title gets a
+// backslash, because it would start a comment.
+func exportTimeclock(output io.Writer, r *Report, opts ExportOptions) error {
+	w := bufio.NewWriter(output)
+	for _, e := range exportedEntries(r) {
+		account := timeclockAccount(e, opts)
+		if account == "" {
+			continue
+		}
+		if tags := timeclockComment(e); tags != "" {
+			fmt.Fprintf(w, "; %s\n", tags)
+		}
+		if e.Notes != "" {
+			fmt.Fprintf(w, "; %s:%s\n", timeclockNotes, strconv.Quote(e.Notes))
+		}
+		title := timeclockSemicolon.ReplaceAllString(e.Title, `$1\;`)
+		fmt.Fprintf(w, "i %s %s  %s\n", e.Start.Format(timeclockLayout), account, title)
+		fmt.Fprintf(w, "o %s\n", e.Start.Add(e.Duration).Format(timeclockLayout))
+	}
+	return w.Flush()
+}
+
+// timeclockAccount returns the account of e, or an empty string if e
+// is break or ignored time without an account
+func timeclockAccount(e ReportEntry, opts ExportOptions) string {
+	for _, c := range opts.Categories {
+		if c.Name == e.Category && c.Account != "" {
+			return c.Account
+		}
+	}
+	switch {
+	case e.Brk || e.Ignore:
+		return ""
+	case e.Project != "":
+		return e.Project
+	case opts.Account != "":
+		return opts.Account
+	}
+	return DefaultAccount
+}
+
+// timeclockComment returns the tags of the comment before the clock-in of e
+func timeclockComment(e ReportEntry) string {
+	tags := []string{}
+	add := func(name, value string) {
+		if value != "" {
+			tags = append(tags, name+":"+value)
+		}
+	}
+	add(timeclockID, e.ID)
+	if e.Part > 0 {
+		add(timeclockPart, fmt.Sprint(e.Part))
+	}
+	add(timeclockCategory, e.Category)
+	add(timeclockTags, strings.Join(e.Tags, " "))
+	if e.Billable {
+		add(timeclockBillable, "yes")
+	}
+	return strings.Join(tags, ", ")
+}
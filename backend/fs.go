@@ -0,0 +1,41 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+	"github.com/spf13/afero"
+)
+
+// WithFs keeps the timesheet, its backup and lock on fs instead of the
+// OS file system, ie: afero.NewMemMapFs() for an in-memory timesheet
+// Only the OS file system is locked against other processes, other file
+// systems are locked within the Backend.  The bbolt store and omw edit
+// need the OS file system.
+func WithFs(fs afero.Fs) Option {
+	return func(b *Backend) {
+		b.fs = fs
+	}
+}
+
+// isOsFs returns true if fs is the OS file system
+func isOsFs(fs afero.Fs) bool {
+	_, ok := fs.(*afero.OsFs)
+	return ok
+}
+
+// tempFile creates a new file in dir named prefix, a random string and
+// suffix, that only the caller can open
+func tempFile(fs afero.Fs, dir, prefix, suffix string) (afero.File, error) {
+	var err error
+	for i := 0; i < 10; i++ {
+		name := filepath.Join(dir, prefix+uuid.New().String()[:8]+suffix)
+		var f afero.File
+		f, err = fs.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+		if !os.IsExist(err) {
+			return f, err
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,182 @@
+package backend
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// Task is a task string split into its parts
+//
+// A task string is any Unicode text made of whitespace-separated words:
+//
+//	+project   sets the project, only one per task
+//	#tag       adds a tag
+//	**         marks the task as break time
+//	***        marks the task as time to ignore
+//	marker     the marker of a user-defined category, see Category
+//
+// Projects and tags must start with a letter, so "+1" and "#123" stay in
+// the title.  A leading backslash keeps a word in the title as-is, ie: \#hashtag.
+// Markers may also be attached to the last word, ie: lunch**
+type Task struct {
+	Title    string
+	Project  string
+	Tags     []string
+	Category string
+	Marker   string
+}
+
+// ParseTask parses a task string using the markers of the built-in categories
+// Backend also understands the markers of user-defined categories.
+func ParseTask(s string) (*Task, error) {
+	return parseTask(s, builtinMarkers())
+}
+
+// String returns the task in the form that is saved in the timesheet,
+// without the project and tags.  Words of the title that would be read
+// as a project, tag or marker again are escaped, ie: \#hashtag.
+func (t *Task) String() string {
+	return t.format(builtinMarkers())
+}
+
+// format returns the task like String, markers maps the marker of every
+// category to its name like in parseTask
+func (t *Task) format(markers map[string]string) string {
+	title := escapeWords(t.Title, markers)
+	if t.Marker == "" {
+		return title
+	}
+	return title + " " + t.Marker
+}
+
+// builtinMarkers maps the marker of every built-in category to its name
+func builtinMarkers() map[string]string {
+	markers := make(map[string]string, len(BuiltinCategories))
+	for _, c := range BuiltinCategories {
+		markers[c.Marker] = c.Name
+	}
+	return markers
+}
+
+// escapeWords puts a backslash in front of every word of s that parseTask
+// wouldn't keep in the title as-is, so the title reads back unchanged
+func escapeWords(s string, markers map[string]string) string {
+	words := strings.FieldsFunc(s, unicode.IsSpace)
+	for i, word := range words {
+		if t, err := parseTask(word, markers); err != nil || t.Title != word {
+			words[i] = `\` + word
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// parseTask splits s into a Task, markers maps each marker to its category
+func parseTask(s string, markers map[string]string) (*Task, error) {
+	return parseWords(s, markers, true)
+}
+
+// parseSavedTask splits the task of a saved entry into a Task
+// Projects and tags are saved in their own fields, and timesheets from
+// before they existed have words like #1234 in the title, so +project and
+// #tag words stay in the title.  Markers and escapes are still read.
+func parseSavedTask(s string, markers map[string]string) (*Task, error) {
+	return parseWords(s, markers, false)
+}
+
+// parseWords splits s into a Task, reading +project and #tag words if
+// sigils is true
+func parseWords(s string, markers map[string]string, sigils bool) (*Task, error) {
+	if !utf8.ValidString(s) {
+		return nil, errors.Errorf("task %q is not valid UTF-8", s)
+	}
+	for _, r := range s {
+		if unicode.IsControl(r) && !unicode.IsSpace(r) {
+			return nil, errors.Errorf("task %q contains control character %U", s, r)
+		}
+	}
+
+	t := &Task{}
+	words := []string{}
+	setMarker := func(marker string) error {
+		if t.Marker != "" {
+			return errors.Errorf("task %q has more than one marker (%s and %s)", s, t.Marker, marker)
+		}
+		t.Marker = marker
+		t.Category = markers[marker]
+		return nil
+	}
+
+	for _, word := range strings.FieldsFunc(s, unicode.IsSpace) {
+		switch {
+		case strings.HasPrefix(word, `\`) && len(word) > 1:
+			words = append(words, word[1:])
+		case sigils && isSigil(word, '+'):
+			project := word[1:]
+			if t.Project != "" && t.Project != project {
+				return nil, errors.Errorf("task %q has more than one project (+%s and +%s)", s, t.Project, project)
+			}
+			t.Project = project
+		case sigils && isSigil(word, '#'):
+			t.Tags = appendTags(t.Tags, word[1:])
+		case markers[word] != "":
+			err := setMarker(word)
+			if err != nil {
+				return nil, err
+			}
+		case isStars(word) && len(word) > 1:
+			return nil, errors.Errorf("task %q has unknown marker %s", s, word)
+		default:
+			// a marker attached to the end of a word
+			marker := longestMarkerSuffix(word, markers)
+			if marker != "" && isStars(marker) && strings.HasSuffix(strings.TrimSuffix(word, marker), "*") {
+				return nil, errors.Errorf("task %q has unknown marker in %s", s, word)
+			}
+			if marker != "" {
+				err := setMarker(marker)
+				if err != nil {
+					return nil, err
+				}
+				word = strings.TrimSuffix(word, marker)
+			}
+			words = append(words, word)
+		}
+	}
+
+	t.Title = strings.Join(words, " ")
+	if t.Title == "" {
+		return nil, errors.Errorf("task %q has no description", s)
+	}
+	return t, nil
+}
+
+// isSigil returns true if word is sigil followed by a letter
+func isSigil(word string, sigil rune) bool {
+	r, size := utf8.DecodeRuneInString(word)
+	if r != sigil || len(word) == size {
+		return false
+	}
+	next, _ := utf8.DecodeRuneInString(word[size:])
+	return unicode.IsLetter(next)
+}
+
+func isStars(word string) bool {
+	return strings.Trim(word, "*") == ""
+}
+
+// longestMarkerSuffix returns the longest marker that word ends with,
+// as long as something other than the marker is left
+func longestMarkerSuffix(word string, markers map[string]string) string {
+	found := ""
+	for marker := range markers {
+		if len(marker) > len(found) && len(word) > len(marker) && strings.HasSuffix(word, marker) {
+			found = marker
+		}
+	}
+	if found != "" && isStars(strings.TrimSuffix(word, found)) {
+		return ""
+	}
+	return found
+}
@@ -0,0 +1,78 @@
+package backend
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTask(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    *Task
+		wantErr bool
+	}{
+		{"plain", "finish meeting with team", &Task{Title: "finish meeting with team"}, false},
+		{"break", "lunch **", &Task{Title: "lunch", Category: "break", Marker: "**"}, false},
+		{"ignore", "commuting ***", &Task{Title: "commuting", Category: "ignore", Marker: "***"}, false},
+		{"attached marker", "lunch**", &Task{Title: "lunch", Category: "break", Marker: "**"}, false},
+		{"german", "Besprechung über Änderungen", &Task{Title: "Besprechung über Änderungen"}, false},
+		{"japanese", "会議の準備 **", &Task{Title: "会議の準備", Category: "break", Marker: "**"}, false},
+		{"emoji", "☕ coffee 🎉", &Task{Title: "☕ coffee 🎉"}, false},
+		{"project and tags", "review PR +omw #code #レビュー", &Task{Title: "review PR", Project: "omw", Tags: []string{"code", "レビュー"}}, false},
+		{"numbers stay in title", "fix #123 +1", &Task{Title: "fix #123 +1"}, false},
+		{"escaped", `read about \#hashtags`, &Task{Title: "read about #hashtags"}, false},
+		{"punctuation", "C++ and c# code (v1.2) 50% done!", &Task{Title: "C++ and c# code (v1.2) 50% done!"}, false},
+		{"whitespace", "  tabs\tand   spaces ", &Task{Title: "tabs and spaces"}, false},
+		{"empty", "   ", nil, true},
+		{"only project", "+omw", nil, true},
+		{"two projects", "work +a +b", nil, true},
+		{"two markers", "lunch ** ***", nil, true},
+		{"unknown marker", "lunch ****", nil, true},
+		{"unknown attached marker", "lunch****", nil, true},
+		{"invalid utf8", "bad \xff", nil, true},
+		{"control character", "bell \a", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTask(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTask() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTask() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTask_String(t *testing.T) {
+	tests := []struct {
+		name string
+		task Task
+		want string
+	}{
+		{"plain", Task{Title: "review"}, "review"},
+		{"marker", Task{Title: "lunch", Category: "break", Marker: "**"}, "lunch **"},
+		{"sigils", Task{Title: "#hashtag meeting +notaproject"}, `\#hashtag meeting \+notaproject`},
+		{"numbers", Task{Title: "fix #123 +1"}, "fix #123 +1"},
+		{"markers in title", Task{Title: "lunch ** and coffee**", Marker: "***"}, `lunch \** and \coffee** ***`},
+		{"unknown marker in title", Task{Title: "rate ****"}, `rate \****`},
+		{"backslash", Task{Title: `\n and \`}, `\\n and \`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.task.String()
+			if got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+			parsed, err := ParseTask(got)
+			if err != nil {
+				t.Fatalf("ParseTask() error = %v", err)
+			}
+			if parsed.Title != tt.task.Title || parsed.Marker != tt.task.Marker {
+				t.Errorf("ParseTask() = %+v, want %+v", parsed, tt.task)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package backend
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// GridWeek is a timesheet grid with a column for each day of the week
+const GridWeek = "week"
+
+// Grid is a timesheet for one week, in the shape most timesheet forms
+// ask for, with a row for each task or project and a column for each day.
+// Hours are decimal hours rounded to 2 places, totals add up the rounded
+// hours.  Only task time is included, not breaks or ignored time.
+type Grid struct {
+	Week      time.Time   `json:"week"`
+	Rows      string      `json:"rows"`
+	Days      []time.Time `json:"days"`
+	Lines     []GridLine  `json:"lines"`
+	DayTotals []float64   `json:"dayTotals"`
+	Total     float64     `json:"total"`
+}
+
+// GridLine is a row of a Grid
+type GridLine struct {
+	Key   string    `json:"key"`
+	Hours []float64 `json:"hours"`
+	Total float64   `json:"total"`
+}
+
+// WithGrid adds a timesheet grid for each week of the report, with a row
+// for each task title or project.  Use "week" for period and "task" or
+// "project" for rows.
+func WithGrid(period, rows string) ReportOption {
+	return func(o *reportOptions) {
+		o.grid = period
+		o.gridRows = rows
+	}
+}
+
+func validateGrid(period, rows string) error {
+	if period != GridWeek {
+		return errors.Errorf("unknown grid %q - use week", period)
+	}
+	if rows != GroupTask && rows != GroupProject {
+		return errors.Errorf("can't use %q as grid rows - use task or project", rows)
+	}
+	return nil
+}
+
+// buildGrids returns a grid for every week from the start of the report
+// to the end
+// With group rounding, each cell is rounded, carrying over along the row.
+func (b *Backend) buildGrids(report *Report, rows string, rounding *Rounding) []Grid {
+	if rounding != nil && rounding.Scope != RoundGroup {
+		rounding = nil
+	}
+	first := b.dayOf(report.From)
+	first = first.AddDate(0, 0, -((int(first.Weekday()) - int(b.config.weekStart) + 7) % 7))
+	last := b.dayOf(report.To)
+	if report.To.After(report.From) && b.dayBoundary(last).Equal(report.To) {
+		// the report ends at the start of this day
+		last = last.AddDate(0, 0, -1)
+	}
+
+	grids := []Grid{}
+	for week := first; !week.After(last); week = week.AddDate(0, 0, 7) {
+		grid := Grid{Week: week, Rows: rows, DayTotals: make([]float64, 7)}
+		for i := 0; i < 7; i++ {
+			grid.Days = append(grid.Days, week.AddDate(0, 0, i))
+		}
+		byKey := map[string][]time.Duration{}
+		for _, e := range report.Entries {
+			if e.Brk || e.Ignore || e.Duration == 0 {
+				continue
+			}
+			day := daysBetween(week, e.Day)
+			if day < 0 || day > 6 {
+				continue
+			}
+			key := b.groupKeys(e, rows)[0]
+			if byKey[key] == nil {
+				byKey[key] = make([]time.Duration, 7)
+			}
+			byKey[key][day] += e.Duration
+		}
+		keys := make([]string, 0, len(byKey))
+		for key := range byKey {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			line := GridLine{Key: key}
+			r := newRounder(rounding)
+			for day, d := range byKey[key] {
+				hours := decimalHours(r.round(d, KindTask))
+				line.Hours = append(line.Hours, hours)
+				line.Total = roundHours(line.Total + hours)
+				grid.DayTotals[day] = roundHours(grid.DayTotals[day] + hours)
+			}
+			grid.Total = roundHours(grid.Total + line.Total)
+			grid.Lines = append(grid.Lines, line)
+		}
+		grids = append(grids, grid)
+	}
+	return grids
+}
+
+// decimalHours returns d in hours, rounded to 2 decimal places
+func decimalHours(d time.Duration) float64 {
+	return roundHours(d.Hours())
+}
+
+func roundHours(h float64) float64 {
+	return math.Round(h*100) / 100
+}
+
+// formatHours formats hours for a text grid, leaving empty cells blank
+func formatHours(h float64) string {
+	if h == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%6.2f", h)
+}
+
+// gridTitle is the heading of the first column of a grid
+func gridTitle(rows string) string {
+	return strings.ToUpper(rows[:1]) + rows[1:]
+}
+
+// gridText renders a grid as a text table
+func gridText(grid Grid) (string, error) {
+	var output strings.Builder
+	w := tabwriter.NewWriter(&output, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(&output, "\n----------------------- Week of %s -----------------------\n\n", grid.Week.Format("2006-01-02"))
+	header := []string{gridTitle(grid.Rows)}
+	for _, day := range grid.Days {
+		header = append(header, day.Format("Mon 02"))
+	}
+	fmt.Fprintln(w, strings.Join(append(header, "Total"), "\t")+"\t")
+	for _, line := range grid.Lines {
+		cells := []string{line.Key}
+		for _, h := range line.Hours {
+			cells = append(cells, formatHours(h))
+		}
+		fmt.Fprintln(w, strings.Join(append(cells, formatHours(line.Total)), "\t")+"\t")
+	}
+	totals := []string{"Total"}
+	for _, h := range grid.DayTotals {
+		totals = append(totals, formatHours(h))
+	}
+	fmt.Fprintln(w, strings.Join(append(totals, formatHours(grid.Total)), "\t")+"\t")
+	if err := w.Flush(); err != nil {
+		return "", err
+	}
+	return output.String(), nil
+}
+
+// writeGridsCSV renders grids as a single CSV table, with the week in the
+// first column and a total row for each week
+func writeGridsCSV(output io.Writer, grids []Grid) error {
+	w := csv.NewWriter(output)
+	for i, grid := range grids {
+		if i == 0 {
+			header := []string{"Week", gridTitle(grid.Rows)}
+			for _, day := range grid.Days {
+				header = append(header, day.Weekday().String())
+			}
+			w.Write(append(header, "Total"))
+		}
+		week := grid.Week.Format("2006-01-02")
+		for _, line := range grid.Lines {
+			record := []string{week, line.Key}
+			for _, h := range line.Hours {
+				record = append(record, fmt.Sprintf("%.2f", h))
+			}
+			w.Write(append(record, fmt.Sprintf("%.2f", line.Total)))
+		}
+		record := []string{week, "Total"}
+		for _, h := range grid.DayTotals {
+			record = append(record, fmt.Sprintf("%.2f", h))
+		}
+		w.Write(append(record, fmt.Sprintf("%.2f", grid.Total)))
+	}
+	w.Flush()
+	return w.Error()
+}
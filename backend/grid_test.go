@@ -0,0 +1,48 @@
+package backend
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackend_buildGrids(t *testing.T) {
+	monday := time.Date(2020, 1, 13, 0, 0, 0, 0, time.UTC)
+	report := &Report{
+		From: monday.AddDate(0, 0, 2),
+		To:   monday.AddDate(0, 0, 7),
+		Entries: []ReportEntry{
+			{Title: "review", Project: "omw", Day: monday, Duration: 90 * time.Minute},
+			{Title: "review", Project: "omw", Day: monday.AddDate(0, 0, 1), Duration: 20 * time.Minute},
+			{Title: "lunch", Brk: true, Day: monday, Duration: time.Hour},
+			{Title: "standup", Project: "acme", Day: monday.AddDate(0, 0, 1), Duration: 15 * time.Minute},
+		},
+	}
+	b := &Backend{config: &config{weekStart: time.Monday}}
+	grids := b.buildGrids(report, GroupTask, nil)
+	if len(grids) != 1 || !grids[0].Week.Equal(monday) {
+		t.Fatalf("buildGrids() = %+v, want one grid for the week of %v", grids, monday)
+	}
+	want := []GridLine{
+		{Key: "review", Hours: []float64{1.5, 0.33, 0, 0, 0, 0, 0}, Total: 1.83},
+		{Key: "standup", Hours: []float64{0, 0.25, 0, 0, 0, 0, 0}, Total: 0.25},
+	}
+	if !reflect.DeepEqual(grids[0].Lines, want) {
+		t.Errorf("buildGrids() lines = %+v, want %+v", grids[0].Lines, want)
+	}
+	if wantTotals := []float64{1.5, 0.58, 0, 0, 0, 0, 0}; !reflect.DeepEqual(grids[0].DayTotals, wantTotals) || grids[0].Total != 2.08 {
+		t.Errorf("buildGrids() totals = %v %v, want %v 2.08", grids[0].DayTotals, grids[0].Total, wantTotals)
+	}
+
+	var got strings.Builder
+	err := writeGridsCSV(&got, b.buildGrids(report, GroupProject, nil))
+	wantCSV := `Week,Project,Monday,Tuesday,Wednesday,Thursday,Friday,Saturday,Sunday,Total
+2020-01-13,acme,0.00,0.25,0.00,0.00,0.00,0.00,0.00,0.25
+2020-01-13,omw,1.50,0.33,0.00,0.00,0.00,0.00,0.00,1.83
+2020-01-13,Total,1.50,0.58,0.00,0.00,0.00,0.00,0.00,2.08
+`
+	if err != nil || got.String() != wantCSV {
+		t.Errorf("writeGridsCSV() = %q, %v, want %q", got.String(), err, wantCSV)
+	}
+}
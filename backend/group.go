@@ -0,0 +1,159 @@
+package backend
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Report groups, see GroupBy
+const (
+	GroupTask    = "task"
+	GroupProject = "project"
+	GroupTag     = "tag"
+	GroupDay     = "day"
+	GroupWeek    = "week"
+)
+
+// noGroupKey is the key of the group of entries without a project or tag
+const noGroupKey = "(none)"
+
+// ReportOption changes what Report calculates
+type ReportOption func(o *reportOptions)
+
+type reportOptions struct {
+	groupBy  []string
+	grid     string
+	gridRows string
+	rounding *Rounding
+}
+
+// ReportGroup is the total time of the report entries with the same task
+// title, project, tag, day or week.  Groups are nested in the order given
+// to GroupBy.  An entry with more than one tag is counted in each tag group.
+type ReportGroup struct {
+	By        string        `json:"by"`
+	Key       string        `json:"key"`
+	IgnoreHrs time.Duration `json:"ignoreTotalHours"`
+	BrkHrs    time.Duration `json:"breakTotalHours"`
+	TaskHrs   time.Duration `json:"taskTotalHours"`
+	Groups    []ReportGroup `json:"groups,omitempty"`
+	// Depth is how deeply the group is nested, starting at 0
+	Depth int `json:"-"`
+}
+
+// GroupBy adds subtotals to a report, grouped by task, project, tag, day
+// or week.  Use more than one to nest groups, ie: GroupBy("project", "day")
+func GroupBy(keys ...string) ReportOption {
+	return func(o *reportOptions) {
+		o.groupBy = append(o.groupBy, keys...)
+	}
+}
+
+// ParseGroupBy splits a comma-separated list of groups, ie: project,day
+// and checks each of them
+func ParseGroupBy(s string) ([]string, error) {
+	keys := []string{}
+	for _, key := range strings.Split(s, ",") {
+		key = strings.ToLower(strings.TrimSpace(key))
+		if key == "" {
+			continue
+		}
+		if err := validateGroup(key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func validateGroup(key string) error {
+	switch key {
+	case GroupTask, GroupProject, GroupTag, GroupDay, GroupWeek:
+		return nil
+	}
+	return errors.Errorf("can't group by %q - use task, project, tag, day or week", key)
+}
+
+// groupEntries totals entries by the first group in by, and then each
+// group by the rest
+// Entries without a duration, like hello, only mark the start of a day
+// and aren't counted.  r rounds the innermost groups, the totals of the
+// other groups add up the rounded totals of their groups.
+func (b *Backend) groupEntries(entries []ReportEntry, by []string, depth int, r *rounder) []ReportGroup {
+	if len(by) == 0 {
+		return nil
+	}
+	byKey := map[string][]ReportEntry{}
+	for _, e := range entries {
+		if e.Duration == 0 {
+			continue
+		}
+		for _, key := range b.groupKeys(e, by[0]) {
+			byKey[key] = append(byKey[key], e)
+		}
+	}
+	keys := make([]string, 0, len(byKey))
+	for key := range byKey {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	groups := []ReportGroup{}
+	for _, key := range keys {
+		g := ReportGroup{By: by[0], Key: key, Depth: depth}
+		g.Groups = b.groupEntries(byKey[key], by[1:], depth+1, r)
+		if len(g.Groups) > 0 {
+			for _, child := range g.Groups {
+				g.TaskHrs += child.TaskHrs
+				g.BrkHrs += child.BrkHrs
+				g.IgnoreHrs += child.IgnoreHrs
+			}
+			groups = append(groups, g)
+			continue
+		}
+		for _, e := range byKey[key] {
+			switch entryKind(e) {
+			case KindIgnore:
+				g.IgnoreHrs += e.Duration
+			case KindBreak:
+				g.BrkHrs += e.Duration
+			default:
+				g.TaskHrs += e.Duration
+			}
+		}
+		g.TaskHrs = r.round(g.TaskHrs, KindTask)
+		g.BrkHrs = r.round(g.BrkHrs, KindBreak)
+		g.IgnoreHrs = r.round(g.IgnoreHrs, KindIgnore)
+		groups = append(groups, g)
+	}
+	return groups
+}
+
+// groupKeys returns the keys of the groups that e is counted in
+// Days and weeks use the date they start on, so they sort in order.
+func (b *Backend) groupKeys(e ReportEntry, by string) []string {
+	switch by {
+	case GroupTask:
+		// stretched tasks have the same title
+		return []string{e.Title}
+	case GroupProject:
+		if e.Project == "" {
+			return []string{noGroupKey}
+		}
+		return []string{e.Project}
+	case GroupTag:
+		if len(e.Tags) == 0 {
+			return []string{noGroupKey}
+		}
+		return e.Tags
+	case GroupDay:
+		return []string{e.Day.Format("2006-01-02")}
+	case GroupWeek:
+		first := e.Day.AddDate(0, 0, -((int(e.Day.Weekday()) - int(b.config.weekStart) + 7) % 7))
+		return []string{first.Format("2006-01-02")}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package backend
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBackend_groupEntries(t *testing.T) {
+	monday := time.Date(2020, 1, 13, 0, 0, 0, 0, time.UTC)
+	entries := []ReportEntry{
+		{ID: "1", Title: "hello", Day: monday},
+		{ID: "2", Title: "review", Project: "omw", Tags: []string{"code"}, Day: monday, Duration: time.Hour},
+		{ID: "3", Title: "review", Project: "omw", Tags: []string{"code"}, Day: monday, Duration: time.Hour},
+		{ID: "4", Title: "lunch", Brk: true, Day: monday, Duration: time.Hour},
+		{ID: "5", Title: "standup", Project: "acme", Tags: []string{"meeting", "code"}, Day: monday.AddDate(0, 0, 1), Duration: 30 * time.Minute},
+		{ID: "6", Title: "review", Project: "omw", Day: monday.AddDate(0, 0, 6), Duration: time.Hour},
+	}
+	b := &Backend{config: &config{weekStart: time.Monday}}
+	tests := []struct {
+		name string
+		by   []string
+		want []ReportGroup
+	}{
+		{"task", []string{GroupTask}, []ReportGroup{
+			{By: "task", Key: "lunch", BrkHrs: time.Hour},
+			{By: "task", Key: "review", TaskHrs: 3 * time.Hour},
+			{By: "task", Key: "standup", TaskHrs: 30 * time.Minute},
+		}},
+		{"tag", []string{GroupTag}, []ReportGroup{
+			{By: "tag", Key: noGroupKey, TaskHrs: time.Hour, BrkHrs: time.Hour},
+			{By: "tag", Key: "code", TaskHrs: 2*time.Hour + 30*time.Minute},
+			{By: "tag", Key: "meeting", TaskHrs: 30 * time.Minute},
+		}},
+		{"project and day", []string{GroupProject, GroupDay}, []ReportGroup{
+			{By: "project", Key: noGroupKey, BrkHrs: time.Hour, Groups: []ReportGroup{
+				{By: "day", Key: "2020-01-13", BrkHrs: time.Hour, Depth: 1},
+			}},
+			{By: "project", Key: "acme", TaskHrs: 30 * time.Minute, Groups: []ReportGroup{
+				{By: "day", Key: "2020-01-14", TaskHrs: 30 * time.Minute, Depth: 1},
+			}},
+			{By: "project", Key: "omw", TaskHrs: 3 * time.Hour, Groups: []ReportGroup{
+				{By: "day", Key: "2020-01-13", TaskHrs: 2 * time.Hour, Depth: 1},
+				{By: "day", Key: "2020-01-19", TaskHrs: time.Hour, Depth: 1},
+			}},
+		}},
+		{"week", []string{GroupWeek}, []ReportGroup{
+			{By: "week", Key: "2020-01-13", TaskHrs: 3*time.Hour + 30*time.Minute, BrkHrs: time.Hour},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := b.groupEntries(entries, tt.by, 0, newRounder(nil)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("groupEntries() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackend_Report_groupRounding(t *testing.T) {
+	day := time.Date(2020, 1, 13, 0, 0, 0, 0, time.UTC)
+	b, cleanup := newTestBackend(t, []SavedEntry{
+		{ID: "a", End: day.Add(9 * time.Hour), Task: "hello"},
+		{ID: "b", End: day.Add(9*time.Hour + 10*time.Minute), Task: "email"},
+		{ID: "c", End: day.Add(9*time.Hour + 20*time.Minute), Task: "review", Project: "omw"},
+		{ID: "d", End: day.Add(9*time.Hour + 30*time.Minute), Task: "standup", Project: "acme"},
+	})
+	defer cleanup()
+	rounding := Rounding{Increment: 15 * time.Minute, Mode: RoundNearest, Scope: RoundGroup}
+	tests := []struct {
+		name string
+		by   []string
+		want time.Duration
+	}{
+		// 10m each rounds to 15m, but the 30m total to 30m
+		{"no groups", nil, 30 * time.Minute},
+		{"project", []string{GroupProject}, 45 * time.Minute},
+		{"project and day", []string{GroupProject, GroupDay}, 45 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := b.BuildReport(context.Background(), "2020-01-13", "2020-01-13", WithRounding(rounding), GroupBy(tt.by...))
+			if err != nil {
+				t.Fatalf("BuildReport() error = %v", err)
+			}
+			var sum time.Duration
+			for _, g := range report.Groups {
+				sum += g.TaskHrs
+			}
+			if report.TaskHrs != tt.want || (len(tt.by) > 0 && sum != report.TaskHrs) {
+				t.Errorf("BuildReport() TaskHrs = %v, groups add up to %v, want %v", report.TaskHrs, sum, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGroupBy(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    []string
+		wantErr bool
+	}{
+		{"empty", "", []string{}, false},
+		{"one", "task", []string{"task"}, false},
+		{"nested", "Project, day", []string{"project", "day"}, false},
+		{"unknown", "client", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseGroupBy(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseGroupBy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseGroupBy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
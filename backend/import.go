@@ -0,0 +1,238 @@
+package backend
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// OpImport is the journal operation of Import
+const OpImport = "import"
+
+// untrackedTask is the task of the ignored entry that Import adds for a
+// gap between two intervals on the same day
+const untrackedTask = "untracked"
+
+// Importer reads the entries saved by another time tracker, see RegisterImporter
+type Importer interface {
+	Import(r io.Reader, opts ImportOptions) ([]ImportedEntry, error)
+}
+
+// ImporterFunc is an Importer function
+type ImporterFunc func(r io.Reader, opts ImportOptions) ([]ImportedEntry, error)
+
+// Import calls f
+func (f ImporterFunc) Import(r io.Reader, opts ImportOptions) ([]ImportedEntry, error) {
+	return f(r, opts)
+}
+
+// ImportedEntry is an entry read by an Importer
+// Time trackers that only save when a task ends, like omw and UTT, leave
+// Start zero.  Trackers that save the start and end of every interval
+// set both, and Import adds entries for the gaps between intervals.
+type ImportedEntry struct {
+	SavedEntry
+	Start time.Time
+}
+
+// ImportOptions configure an Importer
+type ImportOptions struct {
+	// Location of times without a time zone, the default is time.Local
+	Location *time.Location
+	// Columns maps entry fields, like end or task, to the CSV columns
+	// they are read from, see the csv importer
+	Columns map[string]string
+	// TimeLayout is tried before the built-in layouts to parse times
+	TimeLayout string
+	// DryRun returns the entries that Import would add without saving them
+	DryRun bool
+	// Categories and Account are the ledger accounts of entries, Import
+	// sets them from WithCategories and WithAccount
+	Categories []Category
+	Account    string
+}
+
+// ImportResult describes the entries added by Import
+type ImportResult struct {
+	Added []SavedEntry
+	// Duplicates is the number of imported entries already in the timesheet
+	Duplicates int
+}
+
+var importers = map[string]Importer{}
+
+// RegisterImporter makes an Importer available to Import by name
+func RegisterImporter(name string, imp Importer) {
+	importers[strings.ToLower(name)] = imp
+}
+
+// ImporterNames returns the names of the registered importers
+func ImporterNames() []string {
+	names := make([]string, 0, len(importers))
+	for name := range importers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	RegisterImporter("omwlog", ImporterFunc(importLog))
+	RegisterImporter("utt", ImporterFunc(importLog))
+	RegisterImporter("timewarrior", ImporterFunc(importTimewarrior))
+	RegisterImporter("csv", ImporterFunc(importCSV))
+	RegisterImporter("toggl", ImporterFunc(importToggl))
+	RegisterImporter("clockify", ImporterFunc(importClockify))
+	RegisterImporter("timeclock", ImporterFunc(importTimeclock))
+	RegisterImporter("org", ImporterFunc(importOrg))
+}
+
+// Import reads entries from r with the importer named format and adds
+// them to the timesheet in chronological order.  Entries with the same
+// ID, or the same end time to the minute and task, as an entry in the
+// timesheet are skipped, so a file can be imported again after it grew.
+// Nothing is imported if any entry is invalid, like an entry that Add
+// would reject.  The timesheet is backed up first and the import is recorded in the
+// journal.
+func (b *Backend) Import(ctx context.Context, format string, r io.Reader, opts ImportOptions) (*ImportResult, error) {
+	imp, ok := importers[strings.ToLower(format)]
+	if !ok {
+		return nil, errors.Errorf("unknown import format %q - use %s", format, strings.Join(ImporterNames(), ", "))
+	}
+	if opts.Location == nil {
+		opts.Location = time.Local
+	}
+	opts.Categories = b.config.categories
+	if opts.Account == "" {
+		opts.Account = b.config.account
+	}
+	imported, err := imp.Import(r, opts)
+	if err != nil {
+		return nil, errors.Wrapf(err, "can't import %s", format)
+	}
+	for i, e := range imported {
+		if err := b.validateEntry(e.SavedEntry); err != nil {
+			return nil, errors.Wrapf(err, "can't import %s entry %d", format, i+1)
+		}
+	}
+	fileLock, err := b.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer fileLock.Unlock()
+	existing, err := b.store.List(time.Time{}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	entries := b.importedEntries(imported, existing)
+	result := &ImportResult{Added: []SavedEntry{}}
+	ids := map[string]bool{}
+	seen := map[string]bool{}
+	for _, e := range existing {
+		ids[e.ID] = true
+		seen[b.importKey(e)] = true
+	}
+	for _, e := range entries {
+		if ids[e.ID] || seen[b.importKey(e)] {
+			result.Duplicates++
+			continue
+		}
+		if e.ID == "" {
+			e.ID = uuid.New().String()
+		}
+		ids[e.ID] = true
+		seen[b.importKey(e)] = true
+		result.Added = append(result.Added, e)
+	}
+	if opts.DryRun || len(result.Added) == 0 {
+		return result, nil
+	}
+
+	err = b.backup()
+	if err != nil {
+		return nil, err
+	}
+	merged := existing
+	changes := []Change{}
+	for _, e := range result.Added {
+		merged = insertEntry(merged, e)
+		changes = append(changes, Change{ID: e.ID, After: entryPtr(e)})
+	}
+	err = b.store.ReplaceAll(merged)
+	if err != nil {
+		return nil, err
+	}
+	return result, b.record(OpImport, changes...)
+}
+
+// importedEntries sorts imported entries by time and turns intervals
+// into entries that end at the end of the interval.  A gap before an
+// interval, after the imported or existing entry before it, gets an entry
+// at its start, hello on a new day or untracked time to ignore on the
+// same day.
+func (b *Backend) importedEntries(imported []ImportedEntry, existing []SavedEntry) []SavedEntry {
+	sort.SliceStable(imported, func(i, j int) bool {
+		return importedTime(imported[i]).Before(importedTime(imported[j]))
+	})
+	entries := []SavedEntry{}
+	var last time.Time
+	for _, e := range imported {
+		if !e.Start.IsZero() {
+			if prev := lastBefore(existing, e.Start); prev.After(last) {
+				last = prev
+			}
+		}
+		if !e.Start.IsZero() && (last.IsZero() || e.Start.After(last)) {
+			gap := SavedEntry{End: e.Start, Task: helloTask}
+			if !last.IsZero() && b.dayOf(last).Equal(b.dayOf(e.Start)) {
+				gap = SavedEntry{End: e.Start, Task: untrackedTask, Category: b.ignoreCategory()}
+			}
+			entries = append(entries, gap)
+		}
+		entries = append(entries, e.SavedEntry)
+		if e.End.After(last) {
+			last = e.End
+		}
+	}
+	return entries
+}
+
+// lastBefore returns the end of the last of the chronological entries
+// that ends at or before t
+func lastBefore(entries []SavedEntry, t time.Time) time.Time {
+	var last time.Time
+	for _, e := range entries {
+		if e.End.After(t) {
+			break
+		}
+		last = e.End
+	}
+	return last
+}
+
+// importedTime is the time an imported entry is sorted by
+func importedTime(e ImportedEntry) time.Time {
+	if !e.Start.IsZero() {
+		return e.Start
+	}
+	return e.End
+}
+
+// importKey identifies an entry by its end time, to the minute that most
+// time trackers save, and the title and project of its task, so the same
+// task imported with its project in a separate field still matches
+func (b *Backend) importKey(e SavedEntry) string {
+	entry, _ := b.parseEntry(e)
+	return e.End.UTC().Truncate(time.Minute).Format(time.RFC3339) + " " + entry.Project + " " + entry.Title
+}
+
+// escapeTitle keeps the words of a task from another time tracker in the
+// title, instead of being read as a project, tag or marker
+func escapeTitle(s string) string {
+	return escapeWords(s, builtinMarkers())
+}
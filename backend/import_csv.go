@@ -0,0 +1,283 @@
+package backend
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// CSV fields that ImportOptions.Columns maps to columns
+const (
+	CSVID        = "id"
+	CSVEnd       = "end"
+	CSVStart     = "start"
+	CSVDate      = "date"
+	CSVEndDate   = "end_date"
+	CSVEndTime   = "end_time"
+	CSVStartDate = "start_date"
+	CSVStartTime = "start_time"
+	CSVDuration  = "duration"
+	CSVTask      = "task"
+	CSVProject   = "project"
+	CSVTags      = "tags"
+	CSVNotes     = "notes"
+	CSVBillable  = "billable"
+	CSVCategory  = "category"
+)
+
+var csvFields = []string{
+	CSVID, CSVEnd, CSVStart, CSVDate, CSVEndDate, CSVEndTime, CSVStartDate, CSVStartTime,
+	CSVDuration, CSVTask, CSVProject, CSVTags, CSVNotes, CSVBillable, CSVCategory,
+}
+
+// untitledTask is the task of an imported entry without a description
+const untitledTask = "untitled"
+
+// togglColumns maps the columns of a Toggl Track detailed report CSV
+var togglColumns = map[string]string{
+	CSVTask:      "Description",
+	CSVProject:   "Project",
+	CSVTags:      "Tags",
+	CSVNotes:     "Task",
+	CSVBillable:  "Billable",
+	CSVStartDate: "Start date",
+	CSVStartTime: "Start time",
+	CSVEndDate:   "End date",
+	CSVEndTime:   "End time",
+}
+
+// clockifyColumns maps the columns of a Clockify detailed report CSV
+var clockifyColumns = map[string]string{
+	CSVTask:      "Description",
+	CSVProject:   "Project",
+	CSVTags:      "Tags",
+	CSVNotes:     "Task",
+	CSVBillable:  "Billable",
+	CSVStartDate: "Start Date",
+	CSVStartTime: "Start Time",
+	CSVEndDate:   "End Date",
+	CSVEndTime:   "End Time",
+}
+
+// Layouts tried in order to parse imported times
+var (
+	dateTimeLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04"}
+	dateLayouts     = []string{"2006-01-02", "01/02/2006", "02.01.2006", "2006/01/02"}
+	clockLayouts    = []string{"15:04:05", "15:04", "03:04:05 PM", "03:04 PM", "3:04:05 PM", "3:04 PM"}
+)
+
+func importToggl(r io.Reader, opts ImportOptions) ([]ImportedEntry, error) {
+	opts.Columns = togglColumns
+	return importCSV(r, opts)
+}
+
+func importClockify(r io.Reader, opts ImportOptions) ([]ImportedEntry, error) {
+	opts.Columns = clockifyColumns
+	return importCSV(r, opts)
+}
+
+// importCSV reads a CSV file with a header row, using opts.Columns to
+// find the columns of each field.  Without columns, a column named like
+// a field is used, ie: end, task, project.  An entry needs a task and an
+// end time, which is an end column, an end_time column with an end_date
+// or date column, or a start and a duration.
+func importCSV(r io.Reader, opts ImportOptions) ([]ImportedEntry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err == io.EOF {
+		return []ImportedEntry{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "can't read CSV header")
+	}
+	columns, err := csvColumns(header, opts.Columns)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []ImportedEntry{}
+	for n := 2; ; n++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "can't read CSV line %d", n)
+		}
+		get := func(field string) string {
+			i, ok := columns[field]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		entry, err := csvEntry(get, opts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", n)
+		}
+		entries = append(entries, *entry)
+	}
+	return entries, nil
+}
+
+// csvColumns returns the index in header of the column of each field
+func csvColumns(header []string, mapping map[string]string) (map[string]int, error) {
+	byName := map[string]int{}
+	for i, name := range header {
+		byName[csvName(name)] = i
+	}
+	mapped := map[string]int{}
+	mappedColumns := map[int]bool{}
+	for field, name := range mapping {
+		if !isCSVField(field) {
+			return nil, errors.Errorf("unknown field %q - use %s", field, strings.Join(csvFields, ", "))
+		}
+		i, ok := byName[csvName(name)]
+		if !ok {
+			return nil, errors.Errorf("no column %q for %s", name, field)
+		}
+		mapped[field] = i
+		mappedColumns[i] = true
+	}
+	// fields that aren't mapped are found by name, unless their column
+	// is mapped to another field
+	columns := map[string]int{}
+	for _, field := range csvFields {
+		if i, ok := byName[csvName(field)]; ok && !mappedColumns[i] {
+			columns[field] = i
+		}
+	}
+	for field, i := range mapped {
+		columns[field] = i
+	}
+	return columns, nil
+}
+
+// csvName compares column names without case, and with spaces and
+// underscores as the same character
+func csvName(s string) string {
+	return strings.ToLower(strings.Replace(strings.TrimSpace(s), " ", "_", -1))
+}
+
+func isCSVField(field string) bool {
+	for _, f := range csvFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// csvEntry builds an entry from the fields of a CSV record
+func csvEntry(get func(field string) string, opts ImportOptions) (*ImportedEntry, error) {
+	entry := &ImportedEntry{}
+	var err error
+	entry.Start, err = csvTime(get(CSVStart), firstOf(get(CSVStartDate), get(CSVDate)), get(CSVStartTime), opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "start")
+	}
+	entry.End, err = csvTime(get(CSVEnd), firstOf(get(CSVEndDate), get(CSVDate)), get(CSVEndTime), opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "end")
+	}
+	if d := get(CSVDuration); entry.End.IsZero() && d != "" && !entry.Start.IsZero() {
+		duration, err := parseImportDuration(d)
+		if err != nil {
+			return nil, err
+		}
+		entry.End = entry.Start.Add(duration)
+	}
+	if entry.End.IsZero() {
+		return nil, errors.New("missing end time")
+	}
+	// an end time after midnight on the same date as the start
+	if !entry.Start.IsZero() && entry.End.Before(entry.Start) && get(CSVEnd) == "" && get(CSVEndDate) == "" {
+		entry.End = entry.End.AddDate(0, 0, 1)
+	}
+
+	entry.ID = get(CSVID)
+	entry.Project = get(CSVProject)
+	entry.Notes = get(CSVNotes)
+	entry.Category = get(CSVCategory)
+	entry.Task = escapeTitle(get(CSVTask))
+	if entry.Task == "" {
+		entry.Task = firstOf(escapeTitle(entry.Project), untitledTask)
+	}
+	for _, tag := range strings.FieldsFunc(get(CSVTags), func(r rune) bool { return r == ',' || r == ';' }) {
+		entry.Tags = appendTags(entry.Tags, strings.Join(strings.Fields(tag), "-"))
+	}
+	switch strings.ToLower(get(CSVBillable)) {
+	case "yes", "y", "true", "1", "billable":
+		entry.Billable = true
+	}
+	return entry, nil
+}
+
+// csvTime parses a date and time column, or a date column and a time
+// column, and returns a zero time if they are empty
+func csvTime(value, date, clock string, opts ImportOptions) (time.Time, error) {
+	if value == "" && clock != "" {
+		if date == "" {
+			return time.Time{}, errors.Errorf("missing date for %s", clock)
+		}
+		value = date + " " + clock
+	}
+	if value == "" {
+		return time.Time{}, nil
+	}
+	layouts := append([]string{}, dateTimeLayouts...)
+	for _, d := range dateLayouts {
+		for _, c := range clockLayouts {
+			layouts = append(layouts, d+" "+c)
+		}
+	}
+	if opts.TimeLayout != "" {
+		layouts = append([]string{opts.TimeLayout}, layouts...)
+	}
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, value, opts.Location); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.Errorf("can't parse time %q", value)
+}
+
+// parseImportDuration parses a duration like 1:30:00, 1:30, 1.5 (hours)
+// or 1h30m
+func parseImportDuration(s string) (time.Duration, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, nil
+	}
+	if hours, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(hours * float64(time.Hour)), nil
+	}
+	parts := strings.Split(s, ":")
+	if len(parts) == 2 || len(parts) == 3 {
+		var d time.Duration
+		units := []time.Duration{time.Hour, time.Minute, time.Second}
+		for i, p := range parts {
+			n, err := strconv.Atoi(p)
+			if err != nil {
+				return 0, errors.Errorf("can't parse duration %q", s)
+			}
+			d += time.Duration(n) * units[i]
+		}
+		return d, nil
+	}
+	return 0, errors.Errorf("can't parse duration %q", s)
+}
+
+// firstOf returns the first of values that isn't empty
+func firstOf(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
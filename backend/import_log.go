@@ -0,0 +1,43 @@
+package backend
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// logLayout is the time at the start of every line of a UTT log or an
+// omw log from before the TOML timesheet, ie: 2013-03-23 17:31  lunch **
+// UTT and omw use the same task grammar, so tasks are imported as-is.
+const logLayout = "2006-1-2 15:4"
+
+// importLog reads a UTT or legacy omw log, where each line is the end
+// time and task of an entry.  Blank lines are skipped.
+func importLog(r io.Reader, opts ImportOptions) ([]ImportedEntry, error) {
+	entries := []ImportedEntry{}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 3 {
+			return nil, errors.Errorf("line %d: want a date, time and task", n)
+		}
+		end, err := time.ParseInLocation(logLayout, fields[0]+" "+fields[1], opts.Location)
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", n)
+		}
+		entry := ImportedEntry{}
+		entry.End = end
+		entry.Task = strings.Join(fields[2:], " ")
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
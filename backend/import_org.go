@@ -0,0 +1,92 @@
+package backend
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// orgHeadingLine splits a heading into its stars, title and tags
+	orgHeadingLine = regexp.MustCompile(`^(\*+)\s+(.*?)(?:\s+(:(?:[^\s:]+:)+))?\s*$`)
+	// orgKeyword matches a TODO keyword and a priority at the start of a title
+	orgKeyword = regexp.MustCompile(`^(?:(?:TODO|DONE)\s+)?(?:\[#[A-Z0-9]\]\s+)?`)
+	// orgClockLine matches a closed clock
+	orgClockLine = regexp.MustCompile(`^CLOCK:\s*\[([^\]]+)\]--\[([^\]]+)\]`)
+	// orgTimestamp splits the date and time of a timestamp, the day name
+	// is left out because it depends on the language
+	orgTimestamp = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})(?:\s+[^\d\s]+)?\s+(\d{1,2}:\d{2})$`)
+)
+
+// importOrg reads the closed CLOCK lines of an org-mode file, as written
+// by exportOrg or org-mode.  The task of an entry is the title of the
+// heading the clock is in and the tags are its tags.  A top-level heading
+// is the project of the headings below it.
+func importOrg(r io.Reader, opts ImportOptions) ([]ImportedEntry, error) {
+	entries := []ImportedEntry{}
+	var project, title string
+	var level int
+	var tags []string
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if m := orgHeadingLine.FindStringSubmatch(line); m != nil {
+			level = len(m[1])
+			title = orgKeyword.ReplaceAllString(m[2], "")
+			tags = nil
+			for _, tag := range strings.Split(m[3], ":") {
+				if tag != "" {
+					tags = appendTags(tags, tag)
+				}
+			}
+			if level == 1 {
+				project = title
+			}
+			continue
+		}
+		m := orgClockLine.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		start, err := orgTime(m[1], opts.Location)
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", n)
+		}
+		end, err := orgTime(m[2], opts.Location)
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", n)
+		}
+		if !end.After(start) {
+			continue
+		}
+		entry := ImportedEntry{Start: start}
+		entry.End = end
+		entry.Task = firstOf(escapeTitle(title), untitledTask)
+		if level > 1 {
+			entry.Project = project
+		}
+		entry.Tags = tags
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// orgTime parses the inside of an org-mode timestamp, ie: 2020-01-06 Mon 09:00
+func orgTime(s string, loc *time.Location) (time.Time, error) {
+	m := orgTimestamp.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return time.Time{}, errors.Errorf("can't parse timestamp [%s]", s)
+	}
+	t, err := time.ParseInLocation("2006-01-02 15:04", m[1]+" "+m[2], loc)
+	if err != nil {
+		return time.Time{}, errors.Errorf("can't parse timestamp [%s]", s)
+	}
+	return t, nil
+}
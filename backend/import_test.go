@@ -0,0 +1,359 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestImporters(t *testing.T) {
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2020, 1, day, hour, min, 0, 0, time.UTC)
+	}
+	type imported struct {
+		Start, End time.Time
+		Task       string
+		Project    string
+		Tags       []string
+		Billable   bool
+	}
+	tests := []struct {
+		name    string
+		format  string
+		input   string
+		columns map[string]string
+		want    []imported
+		wantErr bool
+	}{
+		{"utt", "utt", "2020-01-02 09:00  hello\n2020-01-02 10:30  email +acme\n\n2020-1-3 9:5  lunch **\n",
+			nil, []imported{
+				{End: at(2, 9, 0), Task: "hello"},
+				{End: at(2, 10, 30), Task: "email +acme"},
+				{End: at(3, 9, 5), Task: "lunch **"},
+			}, false},
+		{"utt bad line", "utt", "yesterday email\n", nil, nil, true},
+		{"timewarrior data", "timewarrior",
+			"inc 20200106T090000Z - 20200106T100000Z # omw \"code review\" # \"review #42\"\n" +
+				"inc 20200106T103000Z - 20200106T110000Z # standup\n" +
+				"inc 20200106T120000Z\n",
+			nil, []imported{
+				{Start: at(6, 9, 0), End: at(6, 10, 0), Task: "review #42", Tags: []string{"omw", "code-review"}},
+				{Start: at(6, 10, 30), End: at(6, 11, 0), Task: "standup"},
+			}, false},
+		{"timewarrior export", "timewarrior",
+			`[{"id":1,"start":"20200106T090000Z","end":"20200106T093000Z"}]`,
+			nil, []imported{
+				{Start: at(6, 9, 0), End: at(6, 9, 30), Task: untaggedTask},
+			}, false},
+		{"toggl", "toggl",
+			"User,Email,Client,Project,Task,Description,Billable,Start date,Start time,End date,End time,Duration,Tags\n" +
+				"Me,me@x,Acme,Website,Design,Header layout,Yes,2020-01-07,09:00:00,2020-01-07,10:15:00,01:15:00,\"design, ui\"\n",
+			nil, []imported{
+				{Start: at(7, 9, 0), End: at(7, 10, 15), Task: "Header layout", Project: "Website",
+					Tags: []string{"design", "ui"}, Billable: true},
+			}, false},
+		{"clockify", "clockify",
+			"Project,Client,Description,Task,User,Tags,Billable,Start Date,Start Time,End Date,End Time\n" +
+				"Website,Acme,Bugfix,,Me,,No,01/08/2020,01:00 PM,01/08/2020,02:30 PM\n",
+			nil, []imported{
+				{Start: at(8, 13, 0), End: at(8, 14, 30), Task: "Bugfix", Project: "Website"},
+			}, false},
+		{"csv mapped", "csv", "When,What,Client\n2020-01-09 11:00,call,acme\n",
+			map[string]string{CSVEnd: "When", CSVTask: "What", CSVProject: "Client"},
+			[]imported{{End: at(9, 11, 0), Task: "call", Project: "acme"}}, false},
+		{"csv partly mapped", "csv", "When,task,project,tags,billable\n2020-01-09 11:00,call,acme,review,yes\n",
+			map[string]string{CSVEnd: "When", CSVTags: "project"},
+			[]imported{{End: at(9, 11, 0), Task: "call", Tags: []string{"acme"}, Billable: true}}, false},
+		{"csv start and duration", "csv", "start,duration,task\n2020-01-09T11:00:00Z,1h30m,call\n",
+			nil, []imported{{Start: at(9, 11, 0), End: at(9, 12, 30), Task: "call"}}, false},
+		{"timeclock", "timeclock", "; id:x, tags:code review, billable:yes\n" +
+			"i 2020/01/10 09:00:00 acme:web  fix header\n" +
+			"o 2020/01/10 10:30:00\n" +
+			"i 2020-01-10 11:00 omw  lunch  ; category:break\n" +
+			"O 2020-01-10 12:00\n",
+			nil, []imported{
+				{Start: at(10, 9, 0), End: at(10, 10, 30), Task: "fix header", Project: "acme:web", Tags: []string{"code", "review"}, Billable: true},
+				{Start: at(10, 11, 0), End: at(10, 12, 0), Task: "lunch"},
+			}, false},
+		{"timeclock clock-out first", "timeclock", "o 2020/01/10 10:30:00\n", nil, nil, true},
+		{"org", "org", "#+TITLE: work\n" +
+			"* TODO [#A] plan week :admin:\n" +
+			"  :LOGBOOK:\n" +
+			"  CLOCK: [2020-01-13 Mo 09:00]--[2020-01-13 Mo 09:45] =>  0:45\n" +
+			"  :END:\n" +
+			"* acme\n" +
+			"** DONE fix login :bug:web:\n" +
+			":LOGBOOK:\n" +
+			"CLOCK: [2020-01-13 Mon 14:00]\n" +
+			"CLOCK: [2020-01-13 Mon 10:00]--[2020-01-13 Mon 12:30] =>  2:30\n" +
+			":END:\n",
+			nil, []imported{
+				{Start: at(13, 9, 0), End: at(13, 9, 45), Task: "plan week", Tags: []string{"admin"}},
+				{Start: at(13, 10, 0), End: at(13, 12, 30), Task: "fix login", Project: "acme", Tags: []string{"bug", "web"}},
+			}, false},
+		{"org bad timestamp", "org", "* x\nCLOCK: [13.01.2020 10:00]--[13.01.2020 11:00]\n", nil, nil, true},
+		{"csv missing end", "csv", "What\ncall\n", map[string]string{CSVTask: "What"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := ImportOptions{Location: time.UTC, Columns: tt.columns, Account: DefaultAccount}
+			entries, err := importers[tt.format].Import(strings.NewReader(tt.input), opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Import() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got := []imported{}
+			for _, e := range entries {
+				got = append(got, imported{e.Start, e.End, e.Task, e.Project, e.Tags, e.Billable})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Import() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackend_Import(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	b, cleanup := newTestBackend(t, []SavedEntry{
+		{ID: "a", End: day.Add(9 * time.Hour), Task: "hello"},
+		{ID: "b", End: day.Add(10 * time.Hour), Task: "email"},
+	})
+	defer cleanup()
+	intervals := "inc 20200102T110000Z - 20200102T120000Z # review\n" +
+		"inc 20200102T123000Z - 20200102T130000Z # standup\n"
+	opts := ImportOptions{Location: time.UTC}
+
+	dry := opts
+	dry.DryRun = true
+	result, err := b.Import(ctx, "timewarrior", strings.NewReader(intervals), dry)
+	if err != nil {
+		t.Fatalf("Import() dry run error = %v", err)
+	}
+	if len(result.Added) != 4 {
+		t.Errorf("Import() dry run added %d entries, want 4", len(result.Added))
+	}
+	if entries, _ := b.Entries(ctx); len(entries) != 2 {
+		t.Fatalf("Import() dry run saved %d entries, want 2", len(entries))
+	}
+
+	if _, err := b.Import(ctx, "timewarrior", strings.NewReader(intervals), opts); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	entries, err := b.Entries(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, e := range entries {
+		got = append(got, e.End.Format("15:04")+" "+e.Task)
+	}
+	want := []string{"09:00 hello", "10:00 email", "11:00 untracked", "12:00 review", "12:30 untracked", "13:00 standup"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Import() entries = %v, want %v", got, want)
+	}
+	if entries[2].Category != "ignore" {
+		t.Errorf("Import() gap category = %q, want ignore", entries[2].Category)
+	}
+
+	result, err = b.Import(ctx, "timewarrior", strings.NewReader(intervals), opts)
+	if err != nil {
+		t.Fatalf("Import() again error = %v", err)
+	}
+	if len(result.Added) != 0 || result.Duplicates != 2 {
+		t.Errorf("Import() again = %d added, %d duplicates, want 0, 2", len(result.Added), result.Duplicates)
+	}
+
+	if _, err := b.Undo(ctx); err != nil {
+		t.Fatalf("Undo() error = %v", err)
+	}
+	if entries, _ := b.Entries(ctx); len(entries) != 2 {
+		t.Errorf("Undo() left %d entries, want 2", len(entries))
+	}
+
+	if _, err := b.Import(ctx, "xml", strings.NewReader(""), opts); err == nil {
+		t.Error("Import() unknown format error = nil, want an error")
+	}
+}
+
+func TestBackend_Import_invalid(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	b, cleanup := newTestBackend(t, []SavedEntry{{ID: "a", End: day.Add(9 * time.Hour), Task: "hello"}})
+	defer cleanup()
+	// the built-in importers never leave the task empty
+	RegisterImporter("untitled", ImporterFunc(func(r io.Reader, opts ImportOptions) ([]ImportedEntry, error) {
+		return []ImportedEntry{{SavedEntry: SavedEntry{End: day.Add(10 * time.Hour)}}}, nil
+	}))
+	defer delete(importers, "untitled")
+	tests := []struct {
+		name   string
+		format string
+		input  string
+		want   string
+	}{
+		{"unknown category", "csv", "end,task,category\n2020-01-02 10:00,email,\n2020-01-02 11:00,nap,sleep\n",
+			`entry 2: unknown category "sleep"`},
+		{"missing task", "untitled", "", "entry 1: missing task"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, dryRun := range []bool{true, false} {
+				_, err := b.Import(ctx, tt.format, strings.NewReader(tt.input), ImportOptions{Location: time.UTC, DryRun: dryRun})
+				if err == nil || !strings.Contains(err.Error(), tt.want) || errors.Cause(err) != ErrInvalidEntry {
+					t.Errorf("Import() dry run %v error = %v, want %s", dryRun, err, tt.want)
+				}
+			}
+			entries, err := b.Entries(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Errorf("Import() saved %d entries, want none", len(entries)-1)
+			}
+		})
+	}
+}
+
+func TestBackend_Import_csv(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.Local)
+	entries := []SavedEntry{
+		{ID: "a", End: day.Add(9 * time.Hour), Task: "hello"},
+		{ID: "b", End: day.Add(11 * time.Hour), Task: "fix login", Project: "acme", Tags: []string{"bug", "web"}, Notes: "see the logs", Billable: true},
+		{ID: "c", End: day.Add(12 * time.Hour), Task: "lunch **"},
+		{ID: "d", End: day.Add(13 * time.Hour), Task: "review \\#42"},
+	}
+	b, cleanup := newTestBackend(t, entries)
+	defer cleanup()
+	var buf bytes.Buffer
+	if err := b.Export(ctx, "csv", "2020-01-02", "2020-01-02", &buf); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	imported, cleanupImported := newTestBackend(t, nil)
+	defer cleanupImported()
+	if _, err := imported.Import(ctx, "csv", &buf, ImportOptions{}); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	type part struct {
+		ID       string
+		Start    time.Time
+		Duration time.Duration
+		Title    string
+		Project  string
+		Tags     []string
+		Category string
+		Notes    string
+		Billable bool
+	}
+	parts := func(b *Backend) []part {
+		report, err := b.BuildReport(ctx, "2020-01-02", "2020-01-02")
+		if err != nil {
+			t.Fatalf("BuildReport() error = %v", err)
+		}
+		got := []part{}
+		for _, e := range exportedEntries(report) {
+			got = append(got, part{e.ID, e.Start, e.Duration, e.Title, e.Project, e.Tags, e.Category, e.Notes, e.Billable})
+		}
+		return got
+	}
+	want, got := parts(b), parts(imported)
+	if len(want) != 3 {
+		t.Fatalf("BuildReport() = %+v, want 3 entries", want)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Import() = %+v, want %+v", got, want)
+	}
+}
+
+func TestBackend_Import_timeclock(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	entries := []SavedEntry{
+		{ID: "a", End: day.Add(20 * time.Hour), Task: "hello"},
+		{ID: "b", End: day.Add(26 * time.Hour), Task: "night shift", Project: "ops", Tags: []string{"oncall"}, Billable: true,
+			Notes: "paged twice, see \"db\"\nthen restarted"},
+		{ID: "c", End: day.Add(27 * time.Hour), Task: "coffee **"},
+		{ID: "d", End: day.Add(29 * time.Hour), Task: "wrap up ; deploy"},
+	}
+	opts := []Option{
+		WithDayStart(4 * time.Hour),
+		WithDayStartHello(true),
+		WithCategories(Category{Name: "break", Account: "personal:break"}),
+	}
+	b, cleanup := newTestBackend(t, entries, opts...)
+	defer cleanup()
+	var buf bytes.Buffer
+	if err := b.Export(ctx, "timeclock", "2020-01-02", "2020-01-03", &buf); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	imported, cleanupImported := newTestBackend(t, nil, opts...)
+	defer cleanupImported()
+	if _, err := imported.Import(ctx, "timeclock", &buf, ImportOptions{Location: time.UTC}); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	type part struct {
+		ID       string
+		Start    time.Time
+		Duration time.Duration
+		Title    string
+		Project  string
+		Tags     []string
+		Category string
+		Notes    string
+		Billable bool
+	}
+	parts := func(b *Backend) []part {
+		report, err := b.BuildReport(ctx, "2020-01-02", "2020-01-03")
+		if err != nil {
+			t.Fatalf("BuildReport() error = %v", err)
+		}
+		got := []part{}
+		for _, e := range exportedEntries(report) {
+			got = append(got, part{e.ID, e.Start, e.Duration, e.Title, e.Project, e.Tags, e.Category, e.Notes, e.Billable})
+		}
+		return got
+	}
+	want, got := parts(b), parts(imported)
+	if len(want) != 4 {
+		t.Fatalf("BuildReport() = %+v, want 4 entries", want)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Import() = %+v, want %+v", got, want)
+	}
+}
+
+func TestBackend_Import_org(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	b, cleanup := newTestBackend(t, []SavedEntry{
+		{ID: "a", End: day.Add(9 * time.Hour), Task: "hello"},
+		{ID: "b", End: day.Add(10*time.Hour + 30*time.Second), Task: "review", Project: "omw", Tags: []string{"code"}},
+		{ID: "c", End: day.Add(11 * time.Hour), Task: "email"},
+	})
+	defer cleanup()
+	var buf bytes.Buffer
+	if err := b.Export(ctx, "org", "2020-01-02", "2020-01-02", &buf); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	result, err := b.Import(ctx, "org", &buf, ImportOptions{Location: time.UTC})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if len(result.Added) != 0 || result.Duplicates != 2 {
+		t.Errorf("Import() = %d added, %d duplicates, want 0, 2", len(result.Added), result.Duplicates)
+	}
+}
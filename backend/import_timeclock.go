@@ -0,0 +1,155 @@
+package backend
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// timeclockLine matches a clock-in or clock-out line and splits it into
+// the code, date, time and the account and description
+var timeclockLine = regexp.MustCompile(`^([iIoO])\s+(\d{4}[-/.]\d{1,2}[-/.]\d{1,2})\s+(\d{1,2}:\d{2}(?::\d{2})?)(?:\s+(.*))?$`)
+
+// timeclockInlineComment matches the start of a comment after a clock-in
+var timeclockInlineComment = regexp.MustCompile(`\s;`)
+
+// timeclockEscapedSemicolon matches a semicolon escaped by exportTimeclock
+var timeclockEscapedSemicolon = regexp.MustCompile(`(\s)\\;`)
+
+// importTimeclock reads the clock-in and clock-out lines of a timeclock
+// file, as written by exportTimeclock, hledger or ledger.  The account is
+// the project of the entry, unless it is opts.Account or the account of a
+// category.  The tags of a comment before or after a clock-in set the ID,
+// category, tags, notes and billable flag.  Consecutive parts of an entry that
+// was split at the start of a day are joined again.
+func importTimeclock(r io.Reader, opts ImportOptions) ([]ImportedEntry, error) {
+	categories := map[string]string{}
+	for _, c := range opts.Categories {
+		if c.Account != "" {
+			categories[c.Account] = c.Name
+		}
+	}
+	entries := []ImportedEntry{}
+	var open *ImportedEntry
+	tags := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.ContainsAny(line[:1], ";#*%|") {
+			if line[0] == ';' {
+				readTimeclockTags(line[1:], tags)
+			}
+			continue
+		}
+		m := timeclockLine.FindStringSubmatch(line)
+		if m == nil {
+			// other ledger directives, like h and b
+			continue
+		}
+		t, err := timeclockTime(m[2], m[3], opts.Location)
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", n)
+		}
+		if strings.EqualFold(m[1], "i") {
+			if open != nil {
+				return nil, errors.Errorf("line %d: clock-in before the clock-out of %s", n, open.Start.Format(timeclockLayout))
+			}
+			rest := m[4]
+			if loc := timeclockInlineComment.FindStringIndex(rest); loc != nil {
+				readTimeclockTags(rest[loc[1]:], tags)
+				rest = rest[:loc[0]]
+			}
+			open = timeclockEntry(t, rest, tags, categories, opts)
+			tags = map[string]string{}
+			continue
+		}
+		if open == nil {
+			return nil, errors.Errorf("line %d: clock-out without a clock-in", n)
+		}
+		if !t.After(open.Start) {
+			return nil, errors.Errorf("line %d: clock-out at %s is not after the clock-in", n, t.Format(timeclockLayout))
+		}
+		open.End = t
+		if last := len(entries) - 1; last >= 0 && open.ID != "" && open.ID == entries[last].ID && open.Start.Equal(entries[last].End) {
+			entries[last].End = open.End
+		} else {
+			entries = append(entries, *open)
+		}
+		open = nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// timeclockEntry builds an entry from a clock-in line
+func timeclockEntry(start time.Time, rest string, tags, categories map[string]string, opts ImportOptions) *ImportedEntry {
+	account, description := strings.TrimSpace(rest), ""
+	if i := strings.Index(account, "  "); i >= 0 {
+		account, description = strings.TrimSpace(account[:i]), strings.TrimSpace(account[i:])
+	} else if i := strings.Index(account, "\t"); i >= 0 {
+		account, description = strings.TrimSpace(account[:i]), strings.TrimSpace(account[i:])
+	}
+	entry := &ImportedEntry{Start: start}
+	entry.ID = tags[timeclockID]
+	entry.Category = firstOf(tags[timeclockCategory], categories[account])
+	if _, ok := categories[account]; !ok && account != opts.Account {
+		entry.Project = account
+	}
+	description = timeclockEscapedSemicolon.ReplaceAllString(description, "$1;")
+	entry.Task = firstOf(escapeTitle(description), untitledTask)
+	entry.Notes = tags[timeclockNotes]
+	for _, tag := range strings.Fields(tags[timeclockTags]) {
+		entry.Tags = appendTags(entry.Tags, tag)
+	}
+	entry.Billable = strings.EqualFold(tags[timeclockBillable], "yes")
+	return entry
+}
+
+// readTimeclockTags adds the name:value tags of a comment to tags
+// A notes tag takes the rest of the comment, which is unquoted if it is a
+// quoted Go string.
+func readTimeclockTags(comment string, tags map[string]string) {
+	if notes := strings.TrimSpace(comment); strings.HasPrefix(strings.ToLower(notes), timeclockNotes+":") {
+		notes = strings.TrimSpace(notes[len(timeclockNotes)+1:])
+		if unquoted, err := strconv.Unquote(notes); err == nil {
+			notes = unquoted
+		}
+		tags[timeclockNotes] = notes
+		return
+	}
+	for _, field := range strings.Split(comment, ",") {
+		parts := strings.SplitN(field, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		name := strings.ToLower(strings.TrimSpace(parts[0]))
+		if name == "" || strings.ContainsAny(name, " \t") {
+			continue
+		}
+		tags[name] = strings.TrimSpace(parts[1])
+	}
+}
+
+// timeclockTime parses the date and time of a clock-in or clock-out
+func timeclockTime(date, clock string, loc *time.Location) (time.Time, error) {
+	date = strings.NewReplacer("/", "-", ".", "-").Replace(date)
+	layout := "2006-1-2 15:04"
+	if strings.Count(clock, ":") == 2 {
+		layout = "2006-1-2 15:04:05"
+	}
+	t, err := time.ParseInLocation(layout, date+" "+clock, loc)
+	if err != nil {
+		return time.Time{}, errors.Errorf("can't parse time %s %s", date, clock)
+	}
+	return t, nil
+}
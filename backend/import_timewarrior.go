@@ -0,0 +1,154 @@
+package backend
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// timewLayout is the UTC time format of Timewarrior data and exports
+const timewLayout = "20060102T150405Z"
+
+// untaggedTask is the task of an imported interval without tags
+const untaggedTask = "untagged"
+
+// timewInterval is an interval in the output of timew export
+type timewInterval struct {
+	Start      string   `json:"start"`
+	End        string   `json:"end"`
+	Tags       []string `json:"tags"`
+	Annotation string   `json:"annotation"`
+}
+
+// importTimewarrior reads the JSON output of timew export or the lines
+// of a Timewarrior data file, ie: ~/.timewarrior/data/2020-01.data
+//
+//	inc 20200102T090000Z - 20200102T100000Z # omw "code review" # "annotation"
+//
+// The annotation is the task and the tags are tags.  Without an
+// annotation, the tags are the task.  Open intervals are skipped.
+func importTimewarrior(r io.Reader, opts ImportOptions) ([]ImportedEntry, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	intervals := []timewInterval{}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &intervals)
+		if err != nil {
+			return nil, errors.Wrap(err, "can't unmarshal timew export")
+		}
+	} else {
+		intervals, err = readTimewData(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	entries := []ImportedEntry{}
+	for i, in := range intervals {
+		if in.End == "" {
+			continue
+		}
+		start, err := time.Parse(timewLayout, in.Start)
+		if err != nil {
+			return nil, errors.Wrapf(err, "interval %d", i+1)
+		}
+		end, err := time.Parse(timewLayout, in.End)
+		if err != nil {
+			return nil, errors.Wrapf(err, "interval %d", i+1)
+		}
+		entry := ImportedEntry{Start: start.In(opts.Location)}
+		entry.End = end.In(opts.Location)
+		tags := []string{}
+		for _, tag := range in.Tags {
+			tags = appendTags(tags, strings.Join(strings.Fields(tag), "-"))
+		}
+		switch {
+		case in.Annotation != "":
+			entry.Task = escapeTitle(in.Annotation)
+			if len(tags) > 0 {
+				entry.Tags = tags
+			}
+		case len(in.Tags) > 0:
+			entry.Task = escapeTitle(strings.Join(in.Tags, " "))
+		default:
+			entry.Task = untaggedTask
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// readTimewData reads the inc lines of a Timewarrior data file
+func readTimewData(data []byte) ([]timewInterval, error) {
+	intervals := []timewInterval{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		words := splitQuoted(scanner.Text())
+		if len(words) == 0 {
+			continue
+		}
+		if words[0] != "inc" || len(words) < 2 {
+			return nil, errors.Errorf("line %d: want inc <start> - <end>", n)
+		}
+		in := timewInterval{Start: words[1]}
+		rest := words[2:]
+		if len(rest) >= 2 && rest[0] == "-" {
+			in.End = rest[1]
+			rest = rest[2:]
+		}
+		if len(rest) > 0 && rest[0] == "#" {
+			rest = rest[1:]
+			for i, word := range rest {
+				if word == "#" {
+					in.Annotation = strings.Join(rest[i+1:], " ")
+					rest = rest[:i]
+					break
+				}
+			}
+			in.Tags = rest
+		}
+		intervals = append(intervals, in)
+	}
+	return intervals, scanner.Err()
+}
+
+// splitQuoted splits s into words at whitespace, except inside double
+// quotes.  A backslash escapes the next character in a quoted word.
+func splitQuoted(s string) []string {
+	words := []string{}
+	var word strings.Builder
+	inWord, quoted, escaped := false, false, false
+	for _, r := range s {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+			inWord = true
+		case !quoted && (r == ' ' || r == '\t'):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words
+}
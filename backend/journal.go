@@ -0,0 +1,263 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+// Operations recorded in the journal
+const (
+	OpAdd     = "add"
+	OpHello   = "hello"
+	OpStretch = "stretch"
+	OpEdit    = "edit"
+	OpAmend   = "amend"
+	OpRetitle = "retitle"
+	OpMove    = "move"
+	OpRemove  = "rm"
+	OpUpdate  = "update"
+	OpDelete  = "delete"
+)
+
+// journalLength is how many operations the journal keeps
+const journalLength = 100
+
+// Operation is a change to the timesheet that can be undone, see Undo
+type Operation struct {
+	Time    time.Time `json:"time"`
+	Kind    string    `json:"kind"`
+	Changes []Change  `json:"changes"`
+	// Undone is set by History for an operation that can be redone
+	Undone bool `json:"undone,omitempty"`
+}
+
+// Change is an entry before and after an operation
+// Before is nil for a new entry and After is nil for a removed entry.
+type Change struct {
+	ID     string      `json:"id"`
+	Before *SavedEntry `json:"before,omitempty"`
+	After  *SavedEntry `json:"after,omitempty"`
+}
+
+// Describe summarizes the entries that the operation changed
+func (o Operation) Describe() string {
+	if len(o.Changes) != 1 {
+		return fmt.Sprintf("%d entries", len(o.Changes))
+	}
+	c := o.Changes[0]
+	switch {
+	case c.After == nil:
+		return describeEntry(c.Before)
+	case c.Before != nil && !c.Before.End.Equal(c.After.End):
+		return fmt.Sprintf("%s, was %s", describeEntry(c.After), c.Before.End.Format(time.RFC3339))
+	}
+	return describeEntry(c.After)
+}
+
+// journal is saved as JSON next to the timesheet
+// The last Undone operations have been undone and can be redone.
+type journal struct {
+	Operations []Operation `json:"operations"`
+	Undone     int         `json:"undone"`
+}
+
+// Undo reverts the last operation in the journal, like an add, edit or
+// amend, and returns it.  An entry that was changed again since can't be
+// reverted.  The timesheet is backed up first.
+func (b *Backend) Undo(ctx context.Context) (*Operation, error) {
+	fileLock, err := b.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer fileLock.Unlock()
+	j, err := b.readJournal()
+	if err != nil {
+		return nil, err
+	}
+	if j.Undone >= len(j.Operations) {
+		return nil, errors.New("nothing to undo")
+	}
+	op := j.Operations[len(j.Operations)-1-j.Undone]
+	err = b.applyChanges(op, true)
+	if err != nil {
+		return nil, err
+	}
+	j.Undone++
+	return &op, b.writeJournal(j)
+}
+
+// Redo applies the last operation reverted by Undo again and returns it
+func (b *Backend) Redo(ctx context.Context) (*Operation, error) {
+	fileLock, err := b.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer fileLock.Unlock()
+	j, err := b.readJournal()
+	if err != nil {
+		return nil, err
+	}
+	if j.Undone == 0 {
+		return nil, errors.New("nothing to redo")
+	}
+	op := j.Operations[len(j.Operations)-j.Undone]
+	err = b.applyChanges(op, false)
+	if err != nil {
+		return nil, err
+	}
+	j.Undone--
+	return &op, b.writeJournal(j)
+}
+
+// History returns the operations in the journal, newest first
+func (b *Backend) History(ctx context.Context) ([]Operation, error) {
+	fileLock, err := b.rlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer fileLock.Unlock()
+	j, err := b.readJournal()
+	if err != nil {
+		return nil, err
+	}
+	ops := []Operation{}
+	for i := len(j.Operations) - 1; i >= 0; i-- {
+		op := j.Operations[i]
+		op.Undone = i >= len(j.Operations)-j.Undone
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+// record adds an operation to the journal after the timesheet was changed
+// Operations that were undone can't be redone after a new operation.
+// The caller must hold the lock.
+func (b *Backend) record(kind string, changes ...Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	j, err := b.readJournal()
+	if err != nil {
+		return errors.Wrap(err, "the timesheet was saved, but can't be undone")
+	}
+	j.Operations = append(j.Operations[:len(j.Operations)-j.Undone], Operation{
+		Time:    b.now(),
+		Kind:    kind,
+		Changes: changes,
+	})
+	j.Undone = 0
+	if len(j.Operations) > journalLength {
+		j.Operations = j.Operations[len(j.Operations)-journalLength:]
+	}
+	err = b.writeJournal(j)
+	if err != nil {
+		return errors.Wrap(err, "the timesheet was saved, but can't be undone")
+	}
+	return nil
+}
+
+// applyChanges changes the entries of op back to how they were before
+// it if undo is true, or to how they were after it
+// The caller must hold the lock.
+func (b *Backend) applyChanges(op Operation, undo bool) error {
+	entries, err := b.store.List(time.Time{}, time.Time{})
+	if err != nil {
+		return err
+	}
+	byID := indexEntries(entries)
+	verb := "redo"
+	if undo {
+		verb = "undo"
+	}
+	changed := map[string]bool{}
+	targets := []SavedEntry{}
+	for _, c := range op.Changes {
+		expect, target := c.After, c.Before
+		if !undo {
+			expect, target = c.Before, c.After
+		}
+		current, exists := byID[c.ID]
+		if exists != (expect != nil) || (exists && !sameEntry(current, *expect)) {
+			return errors.Errorf("can't %s %s of %s - entry %s was changed since, use omw edit",
+				verb, op.Kind, op.Time.Format(time.RFC3339), c.ID)
+		}
+		changed[c.ID] = true
+		if target != nil {
+			targets = append(targets, *target)
+		}
+	}
+
+	kept := []SavedEntry{}
+	for _, e := range entries {
+		if !changed[e.ID] {
+			kept = append(kept, e)
+		}
+	}
+	sort.SliceStable(targets, func(i, j int) bool {
+		return targets[i].End.Before(targets[j].End)
+	})
+	for _, e := range targets {
+		kept = insertEntry(kept, e)
+	}
+	err = b.backup()
+	if err != nil {
+		return err
+	}
+	return b.store.ReplaceAll(kept)
+}
+
+// diffEntries returns the changes from before to after, by entry ID
+func diffEntries(before, after []SavedEntry) []Change {
+	beforeByID := indexEntries(before)
+	afterByID := indexEntries(after)
+	changes := []Change{}
+	for _, a := range after {
+		bf, ok := beforeByID[a.ID]
+		switch {
+		case !ok:
+			changes = append(changes, Change{ID: a.ID, After: entryPtr(a)})
+		case !sameEntry(bf, a):
+			changes = append(changes, Change{ID: a.ID, Before: entryPtr(bf), After: entryPtr(a)})
+		}
+	}
+	for _, bf := range before {
+		if _, ok := afterByID[bf.ID]; !ok {
+			changes = append(changes, Change{ID: bf.ID, Before: entryPtr(bf)})
+		}
+	}
+	return changes
+}
+
+func (b *Backend) readJournal() (*journal, error) {
+	j := &journal{}
+	data, err := afero.ReadFile(b.fs, b.config.journalFile)
+	if os.IsNotExist(err) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "can't read journal")
+	}
+	err = json.Unmarshal(data, j)
+	if err != nil {
+		return nil, errors.Wrapf(err, "can't unmarshal journal - remove %s to start a new one", b.config.journalFile)
+	}
+	if j.Undone > len(j.Operations) {
+		j.Undone = len(j.Operations)
+	}
+	return j, nil
+}
+
+func (b *Backend) writeJournal(j *journal) error {
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "can't marshal journal")
+	}
+	return writeFileAtomic(b.fs, b.config.journalFile, data, 0644)
+}
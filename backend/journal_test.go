@@ -0,0 +1,133 @@
+package backend
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBackend_Undo(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	clock := &testClock{now: day.Add(9 * time.Hour)}
+	b, cleanup := newTestBackend(t, nil, WithClock(clock))
+	defer cleanup()
+
+	tasks := func() []string {
+		entries, err := b.Entries(ctx)
+		if err != nil {
+			t.Fatalf("Entries() error = %v", err)
+		}
+		got := []string{}
+		for _, e := range entries {
+			got = append(got, e.Task)
+		}
+		return got
+	}
+	check := func(step string, want ...string) {
+		t.Helper()
+		got := tasks()
+		if len(got) != len(want) {
+			t.Fatalf("%s: entries = %v, want %v", step, got, want)
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Fatalf("%s: entries = %v, want %v", step, got, want)
+			}
+		}
+	}
+
+	if err := b.Hello(ctx); err != nil {
+		t.Fatal(err)
+	}
+	clock.now = clock.now.Add(time.Hour)
+	if err := b.Add(ctx, []string{"email"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.RetitleEntry(ctx, "last", []string{"inbox"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.AmendEntry(ctx, "last", EntryAt(day.Add(8*time.Hour))); err != nil {
+		t.Fatal(err)
+	}
+	check("before undo", "inbox", "hello")
+
+	if op, err := b.Undo(ctx); err != nil || op.Kind != OpMove {
+		t.Fatalf("Undo() = %v, %v, want a move", op, err)
+	}
+	check("undo move", "hello", "inbox")
+	if op, err := b.Undo(ctx); err != nil || op.Kind != OpRetitle {
+		t.Fatalf("Undo() = %v, %v, want a retitle", op, err)
+	}
+	check("undo retitle", "hello", "email")
+	if op, err := b.Redo(ctx); err != nil || op.Kind != OpRetitle {
+		t.Fatalf("Redo() = %v, %v, want a retitle", op, err)
+	}
+	check("redo retitle", "hello", "inbox")
+
+	history, err := b.History(ctx)
+	if err != nil || len(history) != 4 || !history[0].Undone || history[1].Undone {
+		t.Fatalf("History() = %v, %v, want 4 operations with the move undone", history, err)
+	}
+
+	// a new operation drops the move that could be redone
+	if _, err := b.RemoveEntries(ctx, "last"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Redo(ctx); err == nil {
+		t.Errorf("Redo() after a new operation error = nil")
+	}
+	if _, err := b.Undo(ctx); err != nil {
+		t.Fatalf("Undo() rm error = %v", err)
+	}
+	check("undo rm", "hello", "inbox")
+
+	// the retitle can't be undone once the entry changed outside the journal
+	entry, err := b.FindEntry(ctx, "last")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry.Notes = "changed"
+	if err := b.store.Update(*entry); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Undo(ctx); err == nil {
+		t.Errorf("Undo() of a changed entry error = nil")
+	}
+	check("failed undo", "hello", "inbox")
+}
+
+func Test_diffEntries(t *testing.T) {
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	before := []SavedEntry{
+		{ID: "a", End: day, Task: "hello"},
+		{ID: "b", End: day.Add(time.Hour), Task: "email"},
+		{ID: "c", End: day.Add(2 * time.Hour), Task: "review"},
+	}
+	after := []SavedEntry{
+		{ID: "a", End: day, Task: "hello", Tags: []string{}},
+		{ID: "c", End: day.Add(2 * time.Hour), Task: "code review"},
+		{ID: "d", End: day.Add(3 * time.Hour), Task: "lunch **"},
+	}
+	changes := diffEntries(before, after)
+	got := map[string]string{}
+	for _, c := range changes {
+		switch {
+		case c.Before == nil:
+			got[c.ID] = "added"
+		case c.After == nil:
+			got[c.ID] = "removed"
+		default:
+			got[c.ID] = "changed"
+		}
+	}
+	want := map[string]string{"b": "removed", "c": "changed", "d": "added"}
+	if len(got) != len(want) {
+		t.Fatalf("diffEntries() = %v, want %v", got, want)
+	}
+	for id, kind := range want {
+		if got[id] != kind {
+			t.Errorf("diffEntries() %s = %s, want %s", id, got[id], kind)
+		}
+	}
+}
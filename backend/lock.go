@@ -0,0 +1,144 @@
+package backend
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gofrs/flock"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultLockTimeout is how long omw waits for another omw process
+	// to release the timesheet
+	DefaultLockTimeout = 10 * time.Second
+	// lockRetryDelay is how often omw retries a lock held by another process
+	lockRetryDelay = 100 * time.Millisecond
+)
+
+// The timesheet is protected by a lock on a separate file next to the
+// data file.  Stores replace the data file by renaming a new file over it
+// and editors may do the same, which would silently drop a lock held on
+// the data file itself.
+
+// locker is a file lock, implemented by flock.Flock on the OS file system
+type locker interface {
+	TryLockContext(ctx context.Context, retryDelay time.Duration) (bool, error)
+	TryRLockContext(ctx context.Context, retryDelay time.Duration) (bool, error)
+	Unlock() error
+}
+
+// lock waits for the exclusive lock that protects every change to the timesheet
+// The caller must unlock the returned lock
+func (b *Backend) lock(ctx context.Context) (locker, error) {
+	fileLock := b.newLocker()
+	return fileLock, b.waitLock(ctx, fileLock.TryLockContext)
+}
+
+// rlock waits for a shared lock that keeps other processes from changing
+// the timesheet while it is read
+// The caller must unlock the returned lock
+func (b *Backend) rlock(ctx context.Context) (locker, error) {
+	fileLock := b.newLocker()
+	return fileLock, b.waitLock(ctx, fileLock.TryRLockContext)
+}
+
+// newLocker returns a lock on the lock file, which only other processes
+// can see on the OS file system - any other file system, like an
+// in-memory one, is locked within the Backend
+func (b *Backend) newLocker() locker {
+	if isOsFs(b.fs) {
+		return flock.New(b.config.lockFile)
+	}
+	return &memLocker{state: b.locks}
+}
+
+// waitLock retries try until it gets the lock, the lock timeout expires
+// or ctx is cancelled
+func (b *Backend) waitLock(ctx context.Context, try func(context.Context, time.Duration) (bool, error)) error {
+	ctx, cancel := context.WithTimeout(ctx, b.config.lockTimeout)
+	defer cancel()
+	locked, err := try(ctx, lockRetryDelay)
+	if err == context.DeadlineExceeded {
+		return errors.Errorf("timed out after %s waiting for another omw process to release %s", b.config.lockTimeout, b.config.lockFile)
+	}
+	if err != nil {
+		return errors.Wrap(err, "unable to get file lock")
+	}
+	if !locked {
+		return errors.New("unable to get file lock")
+	}
+	return nil
+}
+
+// lockState is shared by the memLockers of a Backend
+type lockState struct {
+	mu      sync.Mutex
+	readers int
+	writer  bool
+}
+
+// memLocker is a reader/writer lock with the same methods as flock.Flock
+type memLocker struct {
+	state     *lockState
+	locked    bool
+	exclusive bool
+}
+
+func (l *memLocker) TryLockContext(ctx context.Context, retryDelay time.Duration) (bool, error) {
+	return l.retry(ctx, retryDelay, func(s *lockState) bool {
+		if s.writer || s.readers > 0 {
+			return false
+		}
+		s.writer = true
+		l.exclusive = true
+		return true
+	})
+}
+
+func (l *memLocker) TryRLockContext(ctx context.Context, retryDelay time.Duration) (bool, error) {
+	return l.retry(ctx, retryDelay, func(s *lockState) bool {
+		if s.writer {
+			return false
+		}
+		s.readers++
+		l.exclusive = false
+		return true
+	})
+}
+
+// retry calls try until it gets the lock or ctx is done
+func (l *memLocker) retry(ctx context.Context, retryDelay time.Duration, try func(s *lockState) bool) (bool, error) {
+	if l.locked {
+		return true, nil
+	}
+	for {
+		l.state.mu.Lock()
+		l.locked = try(l.state)
+		l.state.mu.Unlock()
+		if l.locked {
+			return true, nil
+		}
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
+func (l *memLocker) Unlock() error {
+	if !l.locked {
+		return nil
+	}
+	l.state.mu.Lock()
+	defer l.state.mu.Unlock()
+	if l.exclusive {
+		l.state.writer = false
+	} else {
+		l.state.readers--
+	}
+	l.locked = false
+	return nil
+}
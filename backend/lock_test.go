@@ -0,0 +1,106 @@
+package backend
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofrs/flock"
+	"github.com/spf13/afero"
+)
+
+func TestBackend_lock(t *testing.T) {
+	ctx := context.Background()
+	timeout := 150 * time.Millisecond
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"os", nil},
+		{"memory", []Option{WithFs(afero.NewMemMapFs())}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, cleanup := newTestBackend(t, nil, append(tt.opts, WithLockTimeout(timeout))...)
+			defer cleanup()
+			// another omw process has its own Backend, which only shares
+			// the lock on the OS file system
+			other := b
+			if isOsFs(b.fs) {
+				other = Create(nil, b.config.omwDir, b.config.omwFile, WithLockTimeout(timeout))
+			}
+
+			held, err := b.lock(ctx)
+			if err != nil {
+				t.Fatalf("lock() error = %v", err)
+			}
+			for name, lock := range map[string]func(context.Context) (locker, error){"lock": other.lock, "rlock": other.rlock} {
+				start := time.Now()
+				_, err = lock(ctx)
+				if err == nil || !strings.Contains(err.Error(), "timed out after 150ms") {
+					t.Errorf("%s() while locked error = %v, want a timeout", name, err)
+				}
+				if waited := time.Since(start); waited < timeout {
+					t.Errorf("%s() gave up after %s, want %s", name, waited, timeout)
+				}
+			}
+			held.Unlock()
+
+			readers := []locker{}
+			for _, rlock := range []func(context.Context) (locker, error){b.rlock, other.rlock} {
+				reader, err := rlock(ctx)
+				if err != nil {
+					t.Fatalf("rlock() while read locked error = %v", err)
+				}
+				readers = append(readers, reader)
+			}
+			if _, err := other.lock(ctx); err == nil {
+				t.Error("lock() while read locked error = nil, want a timeout")
+			}
+			for _, reader := range readers {
+				reader.Unlock()
+			}
+
+			writer, err := other.lock(ctx)
+			if err != nil {
+				t.Fatalf("lock() after unlock error = %v", err)
+			}
+			writer.Unlock()
+		})
+	}
+}
+
+func TestBackend_lock_lockFile(t *testing.T) {
+	ctx := context.Background()
+	b, cleanup := newTestBackend(t, nil, WithLockTimeout(100*time.Millisecond))
+	defer cleanup()
+	other := Create(nil, b.config.omwDir, b.config.omwFile, WithLockTimeout(100*time.Millisecond))
+
+	held, err := b.lock(ctx)
+	if err != nil {
+		t.Fatalf("lock() error = %v", err)
+	}
+	defer held.Unlock()
+	if b.config.lockFile != b.config.omwFile+".lock" {
+		t.Errorf("lock file = %s, want %s.lock", b.config.lockFile, b.config.omwFile)
+	}
+	if _, err := os.Stat(b.config.lockFile); err != nil {
+		t.Errorf("lock file error = %v", err)
+	}
+
+	// the data file itself is never locked, so an editor can replace it
+	// without dropping the lock
+	dataLock := flock.New(b.config.omwFile)
+	if locked, err := dataLock.TryLock(); !locked || err != nil {
+		t.Errorf("TryLock() on the data file = %v, %v, want true", locked, err)
+	}
+	dataLock.Unlock()
+	if err := b.store.ReplaceAll([]SavedEntry{{ID: "a", End: time.Now(), Task: "hello"}}); err != nil {
+		t.Fatalf("ReplaceAll() error = %v", err)
+	}
+	if _, err := other.lock(ctx); err == nil {
+		t.Error("lock() after the data file was replaced error = nil, want a timeout")
+	}
+}
@@ -0,0 +1,123 @@
+package backend
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Conflict describes an entry that was changed in omw edit and, at the
+// same time, by another omw process.  Ours or Theirs is nil if that side
+// deleted the entry.
+type Conflict struct {
+	ID     string
+	Ours   *SavedEntry
+	Theirs *SavedEntry
+}
+
+// ConflictError is returned by Edit when the edited timesheet can't be
+// merged with changes saved while the editor was open
+type ConflictError struct {
+	Conflicts []Conflict
+	// Path is where the edited copy of the timesheet was kept
+	Path string
+}
+
+func (e *ConflictError) Error() string {
+	lines := []string{}
+	for _, c := range e.Conflicts {
+		lines = append(lines, fmt.Sprintf("  %s: edited %s, saved by another omw process %s",
+			c.ID, describeEntry(c.Ours), describeEntry(c.Theirs)))
+	}
+	return fmt.Sprintf("%d conflicting change(s) while editing - your edits are kept in %s\n%s",
+		len(e.Conflicts), e.Path, strings.Join(lines, "\n"))
+}
+
+func describeEntry(e *SavedEntry) string {
+	if e == nil {
+		return "(deleted)"
+	}
+	return fmt.Sprintf("%q at %s", e.Task, e.End.Format(time.RFC3339))
+}
+
+// mergeEntries does a three-way merge by entry ID of the timesheet
+// before editing (base), after editing (ours) and as it is saved now (theirs).
+// A change on only one side is kept.  An entry changed differently on
+// both sides, or changed on one side and deleted on the other, is a conflict.
+// The result keeps the entries of ours in the order they were edited, with
+// the entries added by another process inserted by end time.
+func mergeEntries(base, ours, theirs []SavedEntry) ([]SavedEntry, []Conflict) {
+	baseByID := indexEntries(base)
+	oursByID := indexEntries(ours)
+	theirsByID := indexEntries(theirs)
+	merged := []SavedEntry{}
+	conflicts := []Conflict{}
+
+	for _, o := range ours {
+		b, inBase := baseByID[o.ID]
+		t, inTheirs := theirsByID[o.ID]
+		switch {
+		case !inBase && !inTheirs:
+			// added in the editor
+			merged = append(merged, o)
+		case !inBase && inTheirs:
+			// added on both sides with the same ID
+			if !sameEntry(o, t) {
+				conflicts = append(conflicts, Conflict{ID: o.ID, Ours: entryPtr(o), Theirs: entryPtr(t)})
+				continue
+			}
+			merged = append(merged, o)
+		case inBase && !inTheirs:
+			// deleted by another process
+			if !sameEntry(o, b) {
+				conflicts = append(conflicts, Conflict{ID: o.ID, Ours: entryPtr(o)})
+			}
+		case sameEntry(o, b):
+			merged = append(merged, t)
+		case sameEntry(t, b), sameEntry(o, t):
+			merged = append(merged, o)
+		default:
+			conflicts = append(conflicts, Conflict{ID: o.ID, Ours: entryPtr(o), Theirs: entryPtr(t)})
+		}
+	}
+
+	for _, t := range theirs {
+		if _, inOurs := oursByID[t.ID]; inOurs {
+			continue
+		}
+		b, inBase := baseByID[t.ID]
+		switch {
+		case !inBase:
+			// added by another process while editing
+			merged = insertEntry(merged, t)
+		case !sameEntry(t, b):
+			// deleted in the editor, changed by another process
+			conflicts = append(conflicts, Conflict{ID: t.ID, Theirs: entryPtr(t)})
+		}
+	}
+	return merged, conflicts
+}
+
+// sameEntry compares entries at the precision of the TOML timesheet,
+// which only saves whole seconds and leaves out empty tags
+func sameEntry(a, b SavedEntry) bool {
+	a.End = a.End.UTC().Truncate(time.Second)
+	b.End = b.End.UTC().Truncate(time.Second)
+	if len(a.Tags) == 0 && len(b.Tags) == 0 {
+		a.Tags, b.Tags = nil, nil
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func indexEntries(entries []SavedEntry) map[string]SavedEntry {
+	byID := make(map[string]SavedEntry, len(entries))
+	for _, e := range entries {
+		byID[e.ID] = e
+	}
+	return byID
+}
+
+func entryPtr(e SavedEntry) *SavedEntry {
+	return &e
+}
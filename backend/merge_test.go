@@ -0,0 +1,56 @@
+package backend
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_mergeEntries(t *testing.T) {
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	hello := SavedEntry{ID: "a", End: day.Add(9 * time.Hour), Task: "hello"}
+	email := SavedEntry{ID: "b", End: day.Add(10 * time.Hour), Task: "email"}
+	fixed := SavedEntry{ID: "b", End: day.Add(10 * time.Hour), Task: "email and chat"}
+	moved := SavedEntry{ID: "b", End: day.Add(10*time.Hour + 5*time.Minute), Task: "email"}
+	added := SavedEntry{ID: "c", End: day.Add(11 * time.Hour), Task: "review"}
+	typed := SavedEntry{ID: "d", End: day.Add(9*time.Hour + 30*time.Minute), Task: "coffee **"}
+	early := SavedEntry{ID: "e", End: day.Add(9*time.Hour + 45*time.Minute), Task: "standup"}
+	base := []SavedEntry{hello, email}
+
+	tests := []struct {
+		name          string
+		ours          []SavedEntry
+		theirs        []SavedEntry
+		want          []SavedEntry
+		wantConflicts []string
+	}{
+		{"no changes", base, base, base, nil},
+		{"edited only", []SavedEntry{hello, fixed}, base, []SavedEntry{hello, fixed}, nil},
+		{"added while editing", []SavedEntry{hello, fixed}, []SavedEntry{hello, email, added}, []SavedEntry{hello, fixed, added}, nil},
+		{"added before the end while editing", []SavedEntry{hello, fixed}, []SavedEntry{hello, email, early}, []SavedEntry{hello, early, fixed}, nil},
+		{"added on both sides", []SavedEntry{hello, email, typed}, []SavedEntry{hello, email, added}, []SavedEntry{hello, email, typed, added}, nil},
+		{"edited order kept", []SavedEntry{email, hello}, base, []SavedEntry{email, hello}, nil},
+		{"changed by other only", base, []SavedEntry{hello, moved}, []SavedEntry{hello, moved}, nil},
+		{"same change", []SavedEntry{hello, fixed}, []SavedEntry{hello, fixed}, []SavedEntry{hello, fixed}, nil},
+		{"deleted in editor", []SavedEntry{hello}, []SavedEntry{hello, email, added}, []SavedEntry{hello, added}, nil},
+		{"deleted by other", base, []SavedEntry{hello}, []SavedEntry{hello}, nil},
+		{"conflict", []SavedEntry{hello, fixed}, []SavedEntry{hello, moved}, []SavedEntry{hello}, []string{"b"}},
+		{"edited and deleted", []SavedEntry{hello, fixed}, []SavedEntry{hello}, []SavedEntry{hello}, []string{"b"}},
+		{"deleted and changed", []SavedEntry{hello}, []SavedEntry{hello, moved}, []SavedEntry{hello}, []string{"b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, conflicts := mergeEntries(base, tt.ours, tt.theirs)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeEntries() = %v, want %v", got, tt.want)
+			}
+			ids := []string{}
+			for _, c := range conflicts {
+				ids = append(ids, c.ID)
+			}
+			if len(ids) != len(tt.wantConflicts) || (len(ids) > 0 && !reflect.DeepEqual(ids, tt.wantConflicts)) {
+				t.Errorf("mergeEntries() conflicts = %v, want %v", ids, tt.wantConflicts)
+			}
+		})
+	}
+}
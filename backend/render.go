@@ -0,0 +1,88 @@
+package backend
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// Write renders the report to w in one of the formats text, json, fc or
+// csv.  The csv format is a timesheet grid, see WithGrid.
+func (r *Report) Write(w io.Writer, format string) error {
+	switch strings.ToLower(format) {
+	case "", "text":
+		return r.WriteText(w)
+	case "json":
+		return r.WriteJSON(w)
+	case "fc":
+		return r.WriteFC(w)
+	case "csv":
+		return r.WriteCSV(w)
+	}
+	return errors.Errorf("unknown report format %q - use text, json, fc or csv", format)
+}
+
+// WriteText renders the report with TemplateString
+func (r *Report) WriteText(w io.Writer) error {
+	reportTmpl, err := template.New("report").Funcs(template.FuncMap{
+		"indent": func(depth int) string { return strings.Repeat("  ", depth) },
+		"join":   strings.Join,
+		"grid":   gridText,
+	}).Parse(TemplateString)
+	if err != nil {
+		return errors.Wrap(err, "can't parse report template")
+	}
+	err = reportTmpl.Execute(w, r)
+	if err != nil {
+		return errors.Wrap(err, "can't execute report template")
+	}
+	return nil
+}
+
+// WriteJSON renders the whole report as JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(r)
+}
+
+// WriteFC renders the report entries as a FullCalendar event feed
+func (r *Report) WriteFC(w io.Writer) error {
+	events := []FCEvent{}
+	for _, entry := range r.Entries {
+		events = append(events, FCEvent{
+			ID:               exportID(entry),
+			Start:            entry.Start,
+			End:              entry.Start.Add(entry.Duration),
+			Title:            entry.Title,
+			URL:              "",
+			ClassNames:       entry.ClassNames,
+			Color:            entry.Color,
+			Editable:         true,
+			StartEditable:    false,
+			DurationEditable: true,
+			ExtendedProps: FCExtendedProps{
+				EntryID:  entry.ID,
+				Part:     entry.Part,
+				Billable: entry.Billable,
+				Brk:      entry.Brk,
+				Category: entry.Category,
+				Ignore:   entry.Ignore,
+				Notes:    entry.Notes,
+				Project:  entry.Project,
+				Tags:     entry.Tags,
+				Task:     entry.Task,
+			},
+		})
+	}
+	return json.NewEncoder(w).Encode(events)
+}
+
+// WriteCSV renders the timesheet grids of the report as CSV
+func (r *Report) WriteCSV(w io.Writer) error {
+	if len(r.Grids) == 0 {
+		return errors.New("the csv format is a timesheet grid - use it with a grid, ie: --grid week")
+	}
+	return writeGridsCSV(w, r.Grids)
+}
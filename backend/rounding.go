@@ -0,0 +1,122 @@
+package backend
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Rounding modes
+const (
+	RoundNearest = "nearest"
+	RoundUp      = "up"
+	RoundDown    = "down"
+)
+
+// Rounding scopes
+const (
+	// RoundEntry rounds the duration of every entry before it is added up
+	RoundEntry = "entry"
+	// RoundGroup rounds the totals of groups, timesheet grid cells and the report
+	RoundGroup = "group"
+)
+
+// Rounding rounds report durations to an increment, like 6, 15 or 30 minutes
+// With CarryOver, the difference between the rounded and the actual
+// duration is added to the next duration before it is rounded, so totals
+// stay within one increment of the actual time.  Task, break and ignored
+// time each carry over separately.
+type Rounding struct {
+	Increment time.Duration `json:"increment"`
+	Mode      string        `json:"mode"`
+	Scope     string        `json:"scope"`
+	CarryOver bool          `json:"carryOver"`
+}
+
+// String describes the rounding in reports, ie: nearest 15m0s per entry
+func (r Rounding) String() string {
+	s := fmt.Sprintf("%s %s per %s", r.Mode, r.Increment, r.Scope)
+	if r.CarryOver {
+		s += " with carry-over"
+	}
+	return s
+}
+
+// Validate checks the increment, mode and scope
+func (r Rounding) Validate() error {
+	if r.Increment <= 0 {
+		return errors.Errorf("invalid rounding increment %s", r.Increment)
+	}
+	switch r.Mode {
+	case RoundNearest, RoundUp, RoundDown:
+	default:
+		return errors.Errorf("unknown rounding mode %q - use nearest, up or down", r.Mode)
+	}
+	switch r.Scope {
+	case RoundEntry, RoundGroup:
+	default:
+		return errors.Errorf("unknown rounding scope %q - use entry or group", r.Scope)
+	}
+	return nil
+}
+
+// WithRounding rounds the durations and totals of a report
+func WithRounding(r Rounding) ReportOption {
+	return func(o *reportOptions) {
+		o.rounding = &r
+	}
+}
+
+// rounder rounds a series of durations, carrying over the rounding error
+// if that is enabled
+type rounder struct {
+	Rounding
+	carry map[string]time.Duration
+}
+
+// newRounder returns a rounder for r, which rounds nothing if r is nil
+func newRounder(r *Rounding) *rounder {
+	if r == nil {
+		return &rounder{}
+	}
+	return &rounder{Rounding: *r, carry: map[string]time.Duration{}}
+}
+
+// round rounds d, which is time of kind task, break or ignore
+func (r *rounder) round(d time.Duration, kind string) time.Duration {
+	if r.Increment <= 0 || d == 0 {
+		return d
+	}
+	if r.CarryOver {
+		d += r.carry[kind]
+	}
+	rounded := d / r.Increment * r.Increment
+	switch r.Mode {
+	case RoundUp:
+		if rounded < d {
+			rounded += r.Increment
+		}
+	case RoundDown:
+		if rounded > d {
+			rounded -= r.Increment
+		}
+	default:
+		rounded = d.Round(r.Increment)
+	}
+	if r.CarryOver {
+		r.carry[kind] = d - rounded
+	}
+	return rounded
+}
+
+// entryKind returns KindTask, KindBreak or KindIgnore for e
+func entryKind(e ReportEntry) string {
+	switch {
+	case e.Ignore:
+		return KindIgnore
+	case e.Brk:
+		return KindBreak
+	}
+	return KindTask
+}
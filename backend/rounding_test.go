@@ -0,0 +1,57 @@
+package backend
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_rounder_round(t *testing.T) {
+	m := time.Minute
+	durations := []time.Duration{7 * m, 7 * m, 7 * m, 52 * m}
+	tests := []struct {
+		name     string
+		rounding *Rounding
+		want     []time.Duration
+	}{
+		{"none", nil, durations},
+		{"nearest", &Rounding{Increment: 15 * m, Mode: RoundNearest}, []time.Duration{0, 0, 0, 45 * m}},
+		{"up", &Rounding{Increment: 15 * m, Mode: RoundUp}, []time.Duration{15 * m, 15 * m, 15 * m, 60 * m}},
+		{"down", &Rounding{Increment: 6 * m, Mode: RoundDown}, []time.Duration{6 * m, 6 * m, 6 * m, 48 * m}},
+		{"nearest with carry-over", &Rounding{Increment: 15 * m, Mode: RoundNearest, CarryOver: true}, []time.Duration{0, 15 * m, 0, 60 * m}},
+		{"up with carry-over", &Rounding{Increment: 15 * m, Mode: RoundUp, CarryOver: true}, []time.Duration{15 * m, 0, 15 * m, 45 * m}},
+		{"down with carry-over", &Rounding{Increment: 15 * m, Mode: RoundDown, CarryOver: true}, []time.Duration{0, 0, 15 * m, 45 * m}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRounder(tt.rounding)
+			got := []time.Duration{}
+			for _, d := range durations {
+				got = append(got, r.round(d, KindTask))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("round() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRounding_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		rounding Rounding
+		wantErr  bool
+	}{
+		{"valid", Rounding{Increment: 15 * time.Minute, Mode: RoundUp, Scope: RoundGroup}, false},
+		{"no increment", Rounding{Mode: RoundUp, Scope: RoundEntry}, true},
+		{"unknown mode", Rounding{Increment: time.Minute, Mode: "half", Scope: RoundEntry}, true},
+		{"unknown scope", Rounding{Increment: time.Minute, Mode: RoundUp, Scope: "week"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rounding.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
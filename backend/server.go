@@ -2,24 +2,19 @@ package backend
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
-	"io/ioutil"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
 	"runtime"
 	"strings"
-	"text/template"
 	"time"
 
-	"github.com/gofrs/flock"
 	"github.com/google/uuid"
 	"github.com/pelletier/go-toml"
 	"github.com/pkg/errors"
+	"github.com/spf13/afero"
 )
 
 type formatType int
@@ -31,55 +26,100 @@ const (
 	FormatJSON = iota
 	// FormatText indicates that user requested text template report format output
 	FormatText
+	// FormatCSV indicates that user requested a CSV timesheet grid, see WithGrid
+	FormatCSV
 )
 
 func (d formatType) String() string {
-	return [...]string{"FC", "JSON", "Text"}[d]
+	return [...]string{"FC", "JSON", "Text", "CSV"}[d]
 }
 
 // TemplateString defines the template used to output a Report() with FormatText
 var TemplateString = `{{define "Entry"}}
-({{- .Duration}}) {{.Start.Hour}}:{{.Start.Minute}}-{{.Ts.Hour}}:{{.Ts.Minute}} -- {{.Title -}}
+({{- .Duration}}{{if and .Unrounded (ne .Unrounded .Duration)}}, actual {{.Unrounded}}{{end}}) {{.Start.Hour}}:{{.Start.Minute}}-{{.Ts.Hour}}:{{.Ts.Minute}} -- {{.Title}}
+{{- with .Project}} +{{.}}{{end}}{{range .Tags}} #{{.}}{{end}}{{if .Billable}} [billable]{{end}}
+{{- with .Notes}} ({{.}}){{end -}}
 {{end}}
+{{- define "Group"}}
+{{indent .Depth}}{{.Key}}: {{.TaskHrs}}
+{{- if .BrkHrs}} (break {{.BrkHrs}}){{end}}
+{{- if .IgnoreHrs}} (ignore {{.IgnoreHrs}}){{end}}
+{{- range .Groups}}{{template "Group" .}}{{end}}
+{{- end}}
 
 Report Start: {{.From}}
 Report End: {{.To}}
 Total Task Hours: {{.TaskHrs}}
 Total Break Hours: {{.BrkHrs}}
 Total Ignore Hours: {{.IgnoreHrs}}
+{{- with .Rounding}}
+Rounded: {{.}}
+{{- end}}
+{{- range $name, $total := .Totals}}
+{{- if and (ne $name "break") (ne $name "ignore")}}
+Total {{$name}} Hours: {{$total}}
+{{- end}}
+{{- end}}
+{{- range .Warnings}}
+Warning: {{.}}
+{{- end}}
+{{- if .Grids}}
+{{range .Grids}}{{grid .}}{{end}}
+{{- else if .Groups}}
+
+----------------------- By {{join .GroupBy ", "}} -----------------------
+{{range .Groups}}{{template "Group" .}}{{end}}
+{{else}}
 {{$day := "" }}
 {{range .Entries}}
-{{- if ne $day .End.Weekday.String}}
-{{$day = .End.Weekday.String}}
+{{- if ne $day .Day.Weekday.String}}
+{{$day = .Day.Weekday.String}}
 
------------------------ {{$day}}, {{.End.Year}}-{{.End.Month}}-{{.End.Day}} -----------------------
+----------------------- {{$day}}, {{.Day.Year}}-{{.Day.Month}}-{{.Day.Day}} -----------------------
 {{end -}}
 {{- template "Entry" .}}
 {{- end -}}
+{{end -}}
 `
 
 // Backend represents the context and configuration of every instance of the omw command
 // Immediate commands (like omw add, omw report), immediately affect the timesheet
 // Long-running commands (like omw server), maintain a context
 type Backend struct {
-	ctx        context.Context
+	clock      Clock
 	config     *config
 	fp         *os.File
+	fs         afero.Fs
 	lastReport *Report
+	locks      *lockState
+	store      Store
 	worker     *worker
 }
 
+// Option configures a Backend in Create
+type Option func(b *Backend)
+
 // ReportEntry describes a single entry in the timesheet
 // Omw report and the REST API calculate some of the missing
 // from the data stored on disk.
 type ReportEntry struct {
 	ID         string        `json:"id,omitempty"`
+	Billable   bool          `json:"billable,omitempty"`
 	Brk        bool          `json:"break,omitempty"`
+	Category   string        `json:"category,omitempty"`
 	ClassNames []string      `json:"classNames,omitempty"`
+	Day        time.Time     `json:"day"`
+	Color      string        `json:"color,omitempty"`
 	Duration   time.Duration `json:"duration,omitempty"`
+	Unrounded  time.Duration `json:"unroundedDuration,omitempty"`
 	Ignore     bool          `json:"ignore,omitempty"`
+	Notes      string        `json:"notes,omitempty"`
+	Part       int           `json:"part,omitempty"`
+	Project    string        `json:"project,omitempty"`
 	Start      time.Time     `json:"start,omitempty"`
 	End        time.Time     `json:"end,omitempty"`
+	Tags       []string      `json:"tags,omitempty"`
+	Task       string        `json:"task,omitempty"`
 	Title      string        `json:"title,omitempty"`
 	Ts         time.Time     `json:"timestamp,omitempty"`
 	URL        string        `json:"url,omitempty"`
@@ -95,34 +135,97 @@ type SavedItems struct {
 // for each entry.
 // Note that the stored data is minimized to make it
 // more suitable for human consumption
+// The optional fields are only saved when they are set, so older
+// timesheets are still valid
 type SavedEntry struct {
-	ID   string    `toml:"id"`
-	End  time.Time `toml:"end"`
-	Task string    `toml:"task"`
+	ID       string    `toml:"id" json:"id"`
+	End      time.Time `toml:"end" json:"end"`
+	Task     string    `toml:"task" json:"task"`
+	Project  string    `toml:"project,omitempty" json:"project,omitempty"`
+	Tags     []string  `toml:"tags,omitempty" json:"tags,omitempty"`
+	Notes    string    `toml:"notes,omitempty" json:"notes,omitempty"`
+	Billable bool      `toml:"billable,omitempty" json:"billable,omitempty"`
+	Category string    `toml:"category,omitempty" json:"category,omitempty"`
 }
 
 // FCReport describes the format of a FullCalendar-compatible report
 type FCReport struct {
-	Events []ReportEntry `json:"events"`
+	Events []FCEvent `json:"events"`
+}
+
+// FCEvent describes a single FullCalendar event object
+// The start of an entry is the end of the previous entry, so only
+// the duration (the saved end time) of an event can be changed
+type FCEvent struct {
+	ID               string          `json:"id"`
+	Title            string          `json:"title"`
+	Start            time.Time       `json:"start"`
+	End              time.Time       `json:"end"`
+	ClassNames       []string        `json:"classNames,omitempty"`
+	Color            string          `json:"color,omitempty"`
+	URL              string          `json:"url,omitempty"`
+	Editable         bool            `json:"editable"`
+	StartEditable    bool            `json:"startEditable"`
+	DurationEditable bool            `json:"durationEditable"`
+	ExtendedProps    FCExtendedProps `json:"extendedProps"`
+}
+
+// FCExtendedProps holds the omw-specific fields of a FullCalendar event
+// EntryID is the ID of the saved entry, the event ID has the number of the
+// part after it for an entry that is split at the start of a day.
+type FCExtendedProps struct {
+	EntryID  string   `json:"entryId"`
+	Part     int      `json:"part,omitempty"`
+	Billable bool     `json:"billable"`
+	Brk      bool     `json:"break"`
+	Category string   `json:"category,omitempty"`
+	Ignore   bool     `json:"ignore"`
+	Notes    string   `json:"notes,omitempty"`
+	Project  string   `json:"project,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Task     string   `json:"task"`
 }
 
 // Report describes a report
 // previous is only used during report calculation to
 // populate ReportEntry.Duration
 type Report struct {
-	From      time.Time     `json:"reportFrom"`
-	To        time.Time     `json:"reportTo"`
-	IgnoreHrs time.Duration `json:"ignoreTotalHours"`
-	BrkHrs    time.Duration `json:"breakTotalHours"`
-	TaskHrs   time.Duration `json:"taskTotalHours"`
-	Entries   []ReportEntry `json:"entries"`
+	From      time.Time                `json:"reportFrom"`
+	To        time.Time                `json:"reportTo"`
+	IgnoreHrs time.Duration            `json:"ignoreTotalHours"`
+	BrkHrs    time.Duration            `json:"breakTotalHours"`
+	TaskHrs   time.Duration            `json:"taskTotalHours"`
+	Totals    map[string]time.Duration `json:"categoryTotalHours,omitempty"`
+	Rounding  *Rounding                `json:"rounding,omitempty"`
+	GroupBy   []string                 `json:"groupBy,omitempty"`
+	Groups    []ReportGroup            `json:"groups,omitempty"`
+	Grids     []Grid                   `json:"grids,omitempty"`
+	Entries   []ReportEntry            `json:"entries"`
+	Warnings  []string                 `json:"warnings,omitempty"`
 	previous  *time.Time
 }
 
+// ErrNotFound is returned when an entry ID does not exist in the timesheet
+var ErrNotFound = errors.New("entry not found")
+
+// ErrInvalidEntry is returned when an entry is missing required information
+var ErrInvalidEntry = errors.New("invalid entry")
+
 type config struct {
-	omwDir  string
-	omwFile string
-	omwTerm string
+	omwDir        string
+	omwFile       string
+	omwTerm       string
+	editor        string
+	lockFile      string
+	journalFile   string
+	lockTimeout   time.Duration
+	categories    []Category
+	dayStart      time.Duration
+	dayStartHello bool
+	weekStart     time.Weekday
+	periods       []Period
+	account       string
+	jiraProjects  []string
 }
 
 type worker struct {
@@ -131,10 +234,27 @@ type worker struct {
 	rightShiftDown bool
 }
 
-// Add appends the current time and task to your timesheet
-func (b *Backend) Add(args []string) error {
-	task := strings.Join(args, " ")
-	return b.addEntry(task)
+// Add appends the current time and task to your timesheet, or inserts
+// the task at the time set with EntryAt
+// Words in args that start with + or # set the project and tags of the entry
+func (b *Backend) Add(ctx context.Context, args []string, opts ...EntryOption) error {
+	task, err := parseTask(strings.Join(args, " "), b.markers())
+	if err != nil {
+		return err
+	}
+	entry := SavedEntry{
+		Task:    task.format(b.markers()),
+		Project: task.Project,
+		Tags:    task.Tags,
+	}
+	for _, opt := range opts {
+		opt(&entry)
+	}
+	if entry.Task == "" {
+		return errors.New("missing task description")
+	}
+	_, err = b.createEntry(ctx, OpAdd, entry)
+	return err
 }
 
 // Close cleans up before exiting
@@ -145,361 +265,683 @@ func (b *Backend) Close() error {
 	return nil
 }
 
+// CreateEntry adds entry to the timesheet.  A new ID is generated
+// if entry.ID is empty and the current time is used if entry.End is zero.
+// An entry that ends before the last entry is inserted in chronological order.
+func (b *Backend) CreateEntry(ctx context.Context, entry SavedEntry) (*SavedEntry, error) {
+	return b.createEntry(ctx, OpAdd, entry)
+}
+
+// createEntry adds entry and records it in the journal as an operation of kind
+func (b *Backend) createEntry(ctx context.Context, kind string, entry SavedEntry) (*SavedEntry, error) {
+	if entry.ID == "" {
+		entry.ID = uuid.New().String()
+	}
+	if entry.End.IsZero() {
+		entry.End = b.now()
+	}
+	err := b.validateEntry(entry)
+	if err != nil {
+		return nil, err
+	}
+	err = b.appendEntry(ctx, kind, entry)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// DeleteEntry removes the entry with the given ID from the timesheet
+func (b *Backend) DeleteEntry(ctx context.Context, id string) error {
+	fileLock, err := b.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer fileLock.Unlock()
+	entry, err := b.store.Get(id)
+	if err != nil {
+		return err
+	}
+	err = b.backup()
+	if err != nil {
+		return err
+	}
+	err = b.store.Delete(id)
+	if err != nil {
+		return err
+	}
+	return b.record(OpDelete, Change{ID: id, Before: entry})
+}
+
+// Entries returns every entry saved in the timesheet
+func (b *Backend) Entries(ctx context.Context) ([]SavedEntry, error) {
+	fileLock, err := b.rlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer fileLock.Unlock()
+	return b.store.List(time.Time{}, time.Time{})
+}
+
+// Entry returns the entry with the given ID
+func (b *Backend) Entry(ctx context.Context, id string) (*SavedEntry, error) {
+	fileLock, err := b.rlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer fileLock.Unlock()
+	return b.store.Get(id)
+}
+
+// UpdateEntry replaces the saved entry that has the same ID as entry
+func (b *Backend) UpdateEntry(ctx context.Context, entry SavedEntry) error {
+	err := b.validateEntry(entry)
+	if err != nil {
+		return err
+	}
+	fileLock, err := b.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer fileLock.Unlock()
+	before, err := b.store.Get(entry.ID)
+	if err != nil {
+		return err
+	}
+	err = b.backup()
+	if err != nil {
+		return err
+	}
+	err = b.store.Update(entry)
+	if err != nil {
+		return err
+	}
+	return b.record(OpUpdate, Change{ID: entry.ID, Before: before, After: &entry})
+}
+
 // Edit opens your current timesheet in your default editor or
-// in the editor specified by the EDITOR environment variable
+// in the editor set by WithEditor, inside the terminal set by WithTerminal
 // Similar to visudo, will do some basic checks to ensure
 // that any edits will still pass toml.Marshal() and that there
 // are no duplicate IDs
+// The timesheet is not locked while the editor is open, so other omw
+// commands keep working.  When the editor exits, the edits are merged by
+// entry ID with anything saved in the meantime, see mergeEntries().
 // should return true, err to ask the caller to re-run Edit()
-func (b *Backend) Edit() (bool, error) {
-	editor := DefaultEditor
-	fileLock := flock.New(b.config.omwFile)
-	term := DefaultTerm
-
-	locked, err := fileLock.TryLock()
-	defer fileLock.Unlock()
+func (b *Backend) Edit(ctx context.Context) (bool, error) {
+	if !isOsFs(b.fs) {
+		return false, errors.New("omw edit needs a timesheet on the OS file system")
+	}
+	// snapshot timesheet
+	fileLock, err := b.rlock(ctx)
 	if err != nil {
 		return false, err
 	}
-	if !locked {
-		return false, errors.New("unable to get file lock")
-	}
-
-	// copy file
-	source, err := os.Open(b.config.omwFile)
+	source, err := b.dumpTOML()
 	if err != nil {
+		fileLock.Unlock()
 		return false, err
 	}
-	defer source.Close()
-	pat := fmt.Sprintf("%s*", filepath.Base(b.config.omwFile))
-	tmpFile, err := ioutil.TempFile(filepath.Dir(b.config.omwFile), pat)
-	defer tmpFile.Close()
+	snapshot, err := b.store.List(time.Time{}, time.Time{})
+	fileLock.Unlock()
+	if err != nil {
+		return false, errors.Wrap(err, "can't read timesheet - fix it by hand or restore the .bak file")
+	}
+
+	// copy timesheet
+	base := filepath.Base(b.config.omwFile)
+	prefix := strings.TrimSuffix(base, filepath.Ext(base))
+	tmpFile, err := tempFile(b.fs, filepath.Dir(b.config.omwFile), prefix, ".toml")
 	if err != nil {
 		return false, err
 	}
-	_, err = io.Copy(tmpFile, source)
+	tmpPath := tmpFile.Name()
+	_, err = tmpFile.Write(source)
+	tmpFile.Close()
 	if err != nil {
+		b.fs.Remove(tmpPath)
 		return false, err
 	}
 
-	if preferredEditor := os.Getenv("EDITOR"); preferredEditor != "" {
-		editor = preferredEditor
+	// the editor and terminal may include arguments, ie: "code --wait"
+	argv := strings.Fields(b.config.editor)
+	if len(argv) == 0 {
+		argv = []string{DefaultEditor}
 	}
-	runCmd := editor
-	if preferredTerm := os.Getenv("OMW_TERM"); runtime.GOOS != "windows" && preferredTerm != "" {
-		term = preferredTerm
-		runCmd = fmt.Sprintf("%s -e %s", term, editor)
+	if term := strings.Fields(b.config.omwTerm); runtime.GOOS != "windows" && len(term) > 0 {
+		argv = append(append(term, "-e"), argv...)
 	}
-
-	tmpPath := tmpFile.Name()
-	argv := []string{tmpPath}
-	cmd := exec.CommandContext(b.ctx, runCmd, argv...)
+	argv = append(argv, tmpPath)
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
 	// should work if run from terminal
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	err = runCommand(cmd)
 	if err != nil {
-		tmpFile.Close()
-		inner := os.Remove(tmpPath)
-		return false, errors.Wrap(err, inner.Error())
+		b.fs.Remove(tmpPath)
+		return false, err
 	}
 
-	// after edits, lock tmpFile and validate changes
-	tmpLock := flock.New(tmpPath)
-	tmpLocked, err := tmpLock.TryLock()
-	defer tmpLock.Unlock()
+	validated, err := validateEdit(b.fs, tmpPath)
 	if err != nil {
-		tmpFile.Close()
-		inner := os.Remove(tmpPath)
-		return false, errors.Wrap(err, inner.Error())
+		b.fs.Remove(tmpPath)
+		return true, err
 	}
-	if !tmpLocked {
-		tmpFile.Close()
-		err = errors.New("unable to get file lock on tmpFile")
-		inner := os.Remove(tmpPath)
-		return false, errors.Wrap(err, inner.Error())
+	if len(validated.Entries) == 0 {
+		b.fs.Remove(tmpPath)
+		return false, errors.Errorf("got zero entries from edit - manually remove %s to clear all tasks", b.config.omwFile)
 	}
 
-	validated, err := validateEdit(tmpFile.Name())
+	fileLock, err = b.lock(ctx)
 	if err != nil {
-		tmpFile.Close()
-		inner := os.Remove(tmpPath)
-		innerErr := ""
-		if inner != nil {
-			innerErr = inner.Error()
-		}
-		return true, errors.Wrap(err, innerErr)
+		return false, errors.Wrapf(err, "your edits are kept in %s", tmpPath)
 	}
-	if len(validated.Entries) == 0 {
-		return false, errors.Wrapf(err, "got zero entries from edit - manually remove %s to clear all tasks", b.config.omwFile)
-	}
-	validatedBytes, err := toml.Marshal(validated)
+	defer fileLock.Unlock()
+	current, err := b.store.List(time.Time{}, time.Time{})
 	if err != nil {
-		return false, errors.Wrap(err, "can't marshal data in edit")
+		return false, errors.Wrapf(err, "can't read timesheet - your edits are kept in %s", tmpPath)
+	}
+	merged, conflicts := mergeEntries(snapshot, validated.Entries, current)
+	if len(conflicts) > 0 {
+		return false, &ConflictError{Conflicts: conflicts, Path: tmpPath}
 	}
 
 	// backup current file before overwriting
-	input, err := ioutil.ReadFile(b.config.omwFile)
+	err = b.backup()
 	if err != nil {
-		return false, errors.Wrap(err, "reading backup file")
-	}
-	backup := fmt.Sprintf("%s.bak", b.config.omwFile)
-	err = ioutil.WriteFile(backup, input, 0644)
-	if err != nil {
-		return false, errors.Wrap(err, "writing backup file")
+		return false, err
 	}
 
-	err = ioutil.WriteFile(tmpFile.Name(), validatedBytes, 0644)
+	err = b.store.ReplaceAll(merged)
 	if err != nil {
-		return false, errors.Wrap(err, "saving new data")
+		return false, errors.Wrapf(err, "saving new data - your edits are kept in %s", tmpPath)
 	}
-	os.Rename(tmpPath, b.config.omwFile)
-	return false, err
+	b.fs.Remove(tmpPath)
+	return false, b.record(OpEdit, diffEntries(current, merged)...)
 }
 
 // Hello appends a newline and then another line to end of timesheet with current time
 // and the word "Hello".  Meant to be run at the beginning of a new work day
-func (b *Backend) Hello() error {
-	return b.addEntry("hello")
-}
-
-// Report outputs various report formats to one of the following types:
-// Text - command-line default
-// JSON - web default
-// FC   - web fullcalendar JSON feed URL
-// Add 24 hours to the parsed end time so that when a user specifies
-// --from 2019-01-01 --to 2019-01-02
-// that translates to "report on tasks that occurred between 2019-01-01 00:00
-// and "2019-01-03 00:00"
-func (b *Backend) Report(start, end string, format string) (output string, err error) {
-	fcLayout := "2006-01-02T15:04:05-07:00"
-	layout := "2006-1-2" // should support optional leading zeros
-	//layoutEvent := "2006-1-2 15:4"
-	report := Report{}
-	loc := time.Now().Location()
-	report.From, err = time.ParseInLocation(layout, start, loc)
+// Use EntryAt to say hello at an earlier time.
+func (b *Backend) Hello(ctx context.Context, opts ...EntryOption) error {
+	return b.addEntry(ctx, OpHello, helloTask, opts...)
+}
+
+// Report builds a report and renders it in one of the following formats:
+// text - command-line default
+// json - web default
+// fc   - web fullcalendar JSON feed URL
+// csv  - timesheet grid, see WithGrid
+// See BuildReport and Report.Write
+func (b *Backend) Report(ctx context.Context, start, end string, format string, opts ...ReportOption) (string, error) {
+	report, err := b.BuildReport(ctx, start, end, opts...)
 	if err != nil {
-		report.From, err = time.ParseInLocation(fcLayout, start, loc)
+		return "", err
 	}
+	var output strings.Builder
+	err = report.Write(&output, format)
 	if err != nil {
-		return "", errors.Wrap(err, "can't parse report start time")
+		return "", err
 	}
+	return output.String(), nil
+}
 
-	report.To, err = time.ParseInLocation(layout, end, loc)
-	if err != nil {
-		report.To, err = time.ParseInLocation(fcLayout, end, loc)
+// BuildReport calculates the duration of every entry from start to end and
+// adds them up.  The report runs from the start of start to the end of end,
+// see ParseRange, so 2019-01-01 to 2019-01-02 reports on tasks that occurred
+// between 2019-01-01 00:00 and 2019-01-03 00:00
+// An end with a time of day, like the ones FullCalendar sends, is used as given.
+func (b *Backend) BuildReport(ctx context.Context, start, end string, opts ...ReportOption) (*Report, error) {
+	o := reportOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	for _, key := range o.groupBy {
+		if err := validateGroup(key); err != nil {
+			return nil, err
+		}
 	}
+	if o.grid != "" {
+		if err := validateGrid(o.grid, o.gridRows); err != nil {
+			return nil, err
+		}
+	}
+	if o.rounding != nil {
+		if err := o.rounding.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	entryRounder := newRounder(nil)
+	if o.rounding != nil && o.rounding.Scope == RoundEntry {
+		entryRounder = newRounder(o.rounding)
+	}
+	var err error
+	report := Report{}
+	report.From, report.To, err = b.ParseRange(start, end)
 	if err != nil {
-		return "", errors.Wrap(err, "can't parse report end time")
+		return nil, err
 	}
-	report.To = report.To.Add(24 * time.Hour)
-	r, err := ioutil.ReadFile(b.config.omwFile)
+	fileLock, err := b.rlock(ctx)
 	if err != nil {
-		return "", errors.Wrap(err, "can't read data file for report")
+		return nil, err
 	}
-	data := SavedItems{}
-	err = toml.Unmarshal(r, &data)
+	// Read entries from before and after the report as well, to find out
+	// when the first task in the report started and when the last one ends
+	entries, err := b.store.List(report.From.Add(-maxTaskLength), report.To.Add(maxTaskLength))
+	fileLock.Unlock()
 	if err != nil {
-		return "", errors.Wrap(err, "can't unmarshal data")
+		return nil, errors.Wrap(err, "can't read entries for report")
 	}
 
-	for _, e := range data.Entries {
+	for _, e := range entries {
+		// Indicates task starts after the requested time period
+		if e.End.After(report.To) && (report.previous == nil || !report.previous.Before(report.To)) {
+			break
+		}
+		inReport := !e.End.Before(report.From)
 		// Indicates line is missing required information
 		if e.Task == "" {
+			if inReport {
+				report.Warnings = append(report.Warnings, fmt.Sprintf("skipped entry %s at %s with no task", e.ID, e.End.Format(time.RFC3339)))
+			}
 			continue
 		}
 
-		// Indicates task timestamp is outside the requested time period
-		if e.End.Before(report.From) || e.End.After(report.To) {
-			continue
-		}
-		entry, err := b.parseEntry(e.Task)
-		if err != nil {
-			continue
-		}
-		entry.Ts = e.End
-		if err != nil {
-			continue
+		// Entries that can't be parsed are still counted, using
+		// the whole task as the title
+		entry, err := b.parseEntry(e)
+		if err != nil && inReport {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("entry %s at %s: %v", e.ID, e.End.Format(time.RFC3339), err))
 		}
-		// Should indicate first task in requested report time period
-		if report.previous == nil {
-			report.previous = &entry.Ts
-			entry.End = entry.Ts
-			report.Entries = append(report.Entries, *entry)
-			continue
+		// A task starts at the end of the previous entry, unless it is
+		// the first entry of a day - see WithDayStart and WithDayStartHello
+		entry.Start = entry.Ts
+		if report.previous != nil && !b.startsDay(entry, *report.previous) {
+			entry.Start = *report.previous
 		}
-		// For now, we explicitly assume that a new day restarts the duration calculation
-		// We may change the marker from new day to first entry of "hello" on a given day
-		// to better allow tracking tasks that extend from a previous day into a new day
-		if entry.Ts.Day() != (*report.previous).Day() {
-			report.previous = &entry.Ts
-			entry.End = entry.Ts
+		previous := entry.Ts
+		report.previous = &previous
+
+		// A task that runs past the day start is split between both days,
+		// and only the part inside the report is counted
+		for _, part := range b.splitEntry(*entry) {
+			if part.Ts.Before(report.From) || (part.Ts.Equal(report.From) && part.Duration > 0) {
+				continue
+			}
+			if part.Ts.After(report.To) && !part.Start.Before(report.To) {
+				continue
+			}
+			if part.Start.Before(report.From) {
+				part.Start = report.From
+				part.Duration = part.Ts.Sub(part.Start)
+			}
+			if part.Ts.After(report.To) {
+				part.End = report.To
+				part.Duration = part.End.Sub(part.Start)
+			}
+			if entryRounder.Increment > 0 {
+				part.Unrounded = part.Duration
+				part.Duration = entryRounder.round(part.Duration, entryKind(part))
+			}
+			// Use else if to make it clear we only process the event's
+			// duration one time
+			if part.Ignore == false && part.Brk == false {
+				report.TaskHrs += part.Duration
+			} else if part.Ignore == true && part.Brk == false {
+				report.IgnoreHrs += part.Duration
+			} else if part.Ignore == false && part.Brk == true {
+				report.BrkHrs += part.Duration
+			} else if part.Ignore == true && part.Brk == true {
+				return nil, errors.New("entry has both break and ignore set to true")
+			}
+			if part.Category != "" {
+				if report.Totals == nil {
+					report.Totals = map[string]time.Duration{}
+				}
+				report.Totals[part.Category] += part.Duration
+			}
+			report.Entries = append(report.Entries, part)
 		}
-		entry.End = *report.previous
-		entry.Duration = entry.Ts.Sub(*report.previous)
-
-		*report.previous = entry.Ts
-		// Use else if to make it clear we only process the event's
-		// duration one time
-		if entry.Ignore == false && entry.Brk == false {
-			report.TaskHrs += entry.Duration
-		} else if entry.Ignore == true && entry.Brk == false {
-			report.IgnoreHrs += entry.Duration
-		} else if entry.Ignore == false && entry.Brk == true {
-			report.BrkHrs += entry.Duration
-		} else if entry.Ignore == true && entry.Brk == true {
-			return "", errors.New("entry has both break and ignore set to true")
+	}
+	// Group rounding rounds the totals, entry rounding already rounded
+	// every entry before it was added up
+	groupRounder := newRounder(nil)
+	if o.rounding != nil {
+		report.Rounding = o.rounding
+		if o.rounding.Scope == RoundGroup {
+			groupRounder = newRounder(o.rounding)
+			report.TaskHrs = newRounder(o.rounding).round(report.TaskHrs, KindTask)
+			report.BrkHrs = newRounder(o.rounding).round(report.BrkHrs, KindBreak)
+			report.IgnoreHrs = newRounder(o.rounding).round(report.IgnoreHrs, KindIgnore)
+			for name, total := range report.Totals {
+				report.Totals[name] = newRounder(o.rounding).round(total, KindTask)
+			}
 		}
-		report.Entries = append(report.Entries, *entry)
-
 	}
-	f := FormatText
-	if format == "json" {
-		f = FormatJSON
+	if len(o.groupBy) > 0 {
+		report.GroupBy = o.groupBy
+		report.Groups = b.groupEntries(report.Entries, o.groupBy, 0, groupRounder)
+		// With group rounding, the report total is the sum of its rounded
+		// groups, unless entries with more than one tag are counted twice
+		if o.rounding != nil && o.rounding.Scope == RoundGroup && o.groupBy[0] != GroupTag {
+			report.TaskHrs, report.BrkHrs, report.IgnoreHrs = 0, 0, 0
+			for _, g := range report.Groups {
+				report.TaskHrs += g.TaskHrs
+				report.BrkHrs += g.BrkHrs
+				report.IgnoreHrs += g.IgnoreHrs
+			}
+		}
 	}
-	if format == "fc" {
-		f = FormatFC
+	if o.grid != "" {
+		report.Grids = b.buildGrids(&report, o.gridRows, o.rounding)
 	}
 	b.lastReport = &report
-	output, err = b.formatReport(report, formatType(f))
-	if err != nil {
-		return "", err
-	}
-	return output, nil
+	return &report, nil
 }
 
 // Stretch append current timestamp to end of timesheet and copy previous task
-// fp is opened in append mode, so seek to beginning of file first
-func (b *Backend) Stretch() error {
-	r, err := ioutil.ReadFile(b.config.omwFile)
+// along with its project, tags, notes, billable flag and category
+// With EntryAt, the task before that time is stretched to it.
+func (b *Backend) Stretch(ctx context.Context, opts ...EntryOption) error {
+	at := SavedEntry{}
+	for _, opt := range opts {
+		opt(&at)
+	}
+	if at.End.IsZero() {
+		at.End = b.now()
+	}
+	fileLock, err := b.rlock(ctx)
 	if err != nil {
 		return err
 	}
-	data := SavedItems{}
-	err = toml.Unmarshal(r, &data)
+	entries, err := b.store.List(time.Time{}, at.End)
+	fileLock.Unlock()
 	if err != nil {
 		return err
 	}
+	if len(entries) == 0 {
+		return errors.New("no previous task to stretch")
+	}
 
-	lastEntry := data.Entries[len(data.Entries)-1]
+	lastEntry := entries[len(entries)-1]
 	if lastEntry.Task == "" {
 		return errors.New("missing task description for stretch")
 	}
-	err = b.addEntry(lastEntry.Task)
-	if err != nil {
-		return err
-	}
-	return nil
+	lastEntry.ID = ""
+	lastEntry.End = at.End
+	_, err = b.createEntry(ctx, OpStretch, lastEntry)
+	return err
 }
 
-// addEntry seeks to end of file and appends a formatted string
-// will create a new empty file if file is missing
-func (b *Backend) addEntry(s string) error {
-	fp, err := os.OpenFile(b.config.omwFile, os.O_APPEND|os.O_RDWR|os.O_CREATE, 0644)
-	if err != nil {
-		return errors.Wrapf(err, "can't open or create %s: %q", b.config.omwFile, err)
-	}
-	defer fp.Close()
-	data := SavedItems{}
+// addEntry adds a new entry for task s with the current time, or the
+// time set with EntryAt, as an operation of kind
+func (b *Backend) addEntry(ctx context.Context, kind string, s string, opts ...EntryOption) error {
 	entry := SavedEntry{}
+	for _, opt := range opts {
+		opt(&entry)
+	}
 	entry.ID = uuid.New().String()
-	entry.End = time.Now()
+	if entry.End.IsZero() {
+		entry.End = b.now()
+	}
 	entry.Task = s
-	data.Entries = append(data.Entries, entry)
-	entriesBytes, err := toml.Marshal(data)
+	return b.appendEntry(ctx, kind, entry)
+}
+
+// appendEntry adds entry to the end of the timesheet, or inserts it
+// after the last entry that doesn't end after it, and records it in
+// the journal as an operation of kind
+func (b *Backend) appendEntry(ctx context.Context, kind string, entry SavedEntry) error {
+	fileLock, err := b.lock(ctx)
 	if err != nil {
-		return errors.Wrap(err, "can't marshal data")
+		return err
 	}
-	toSave := string(entriesBytes)
-	fileLock := flock.New(b.config.omwFile)
-	locked, err := fileLock.TryLock()
 	defer fileLock.Unlock()
+	later, err := b.store.List(entry.End, time.Time{})
 	if err != nil {
-		return errors.Wrap(err, "unable to get file lock")
+		return err
+	}
+	backdated := false
+	for _, e := range later {
+		if e.End.After(entry.End) {
+			backdated = true
+			break
+		}
 	}
-	if !locked {
-		return errors.New("unable to get file lock")
+	if backdated {
+		err = b.insert(entry)
+	} else {
+		err = b.store.Append(entry)
 	}
-	_, err = fp.WriteString(toSave)
 	if err != nil {
-		return errors.Wrap(err, "error saving new data")
+		return err
 	}
-	return nil
+	return b.record(kind, Change{ID: entry.ID, After: &entry})
 }
 
-func (b *Backend) formatReport(report Report, format formatType) (string, error) {
-	if format == FormatJSON {
-		output, err := json.Marshal(report)
-		return string(output), err
+// insert saves entry in chronological order, after a backup
+func (b *Backend) insert(entry SavedEntry) error {
+	entries, err := b.store.List(time.Time{}, time.Time{})
+	if err != nil {
+		return err
 	}
+	err = b.backup()
+	if err != nil {
+		return err
+	}
+	return b.store.ReplaceAll(insertEntry(entries, entry))
+}
 
-	entries := []ReportEntry{}
-	if format == FormatFC {
-		for _, entry := range report.Entries {
-			classes := []string{}
-			if entry.Brk {
-				classes = append(classes, "breakEntry")
-			}
-			if entry.Ignore {
-				classes = append(classes, "ignoreEntry")
-			}
-
-			entries = append(entries, ReportEntry{
-				Start:      entry.Start,
-				End:        entry.Start.Add(entry.Duration),
-				Title:      entry.Title,
-				URL:        "",
-				ClassNames: classes,
-			})
-		}
-		data := FCReport{
-			Events: entries,
-		}
-		output, err := json.Marshal(data.Events)
-		return string(output), err
+// insertEntry inserts entry after the last of entries that doesn't end after it
+func insertEntry(entries []SavedEntry, entry SavedEntry) []SavedEntry {
+	i := len(entries)
+	for i > 0 && entries[i-1].End.After(entry.End) {
+		i--
 	}
+	return append(entries[:i], append([]SavedEntry{entry}, entries[i:]...)...)
+}
 
-	// fallback to text format
-	reportTmpl, err := template.New("report").Parse(TemplateString)
+// backup saves the current timesheet in TOML format to the same path
+// with a .bak extension
+func (b *Backend) backup() error {
+	input, err := b.dumpTOML()
 	if err != nil {
-		return "", err
+		return errors.Wrap(err, "reading backup file")
+	}
+	backup := fmt.Sprintf("%s.bak", b.config.omwFile)
+	err = writeFileAtomic(b.fs, backup, input, 0644)
+	if err != nil {
+		return errors.Wrap(err, "writing backup file")
 	}
-	err = reportTmpl.Execute(os.Stdout, report)
+	return nil
+}
+
+// dumpTOML returns the entire timesheet in TOML format
+// The TOML store returns the file as-is
+func (b *Backend) dumpTOML() ([]byte, error) {
+	if s, ok := b.store.(*tomlStore); ok {
+		return s.raw()
+	}
+	entries, err := b.store.List(time.Time{}, time.Time{})
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	return "", nil
+	return toml.Marshal(SavedItems{Entries: entries})
+}
+
+// Recovery describes a torn entry that Recover removed from the timesheet
+type Recovery struct {
+	// Path is the timesheet and Damaged is where it was saved before the
+	// torn entry was removed
+	Path    string
+	Damaged string
+	// Removed is the text of the torn entry
+	Removed string
 }
 
-func (b *Backend) parseEntry(s string) (*ReportEntry, error) {
-	re := regexp.MustCompile(`(?P<task>[a-zA-Z0-9,._+:@%\/-]+[a-zA-Z0-9,._+:@%\/\-\t ]*) ?(?P<mod>\*\*\*?)*`)
-	matches := re.FindStringSubmatch(s)
-	if matches == nil {
-		return nil, errors.New("invalid string")
+// Recover checks the timesheet for a torn trailing entry left by a crash
+// and truncates it.  An entry is torn if the file can't be parsed or the
+// last entry has no ID or end time.  It returns what was removed, or nil
+// if there was nothing to recover.
+func (b *Backend) Recover(ctx context.Context) (*Recovery, error) {
+	s, ok := b.store.(*tomlStore)
+	if !ok {
+		return nil, nil
 	}
+	// Only wait for the lock if there is something to fix.  Every write
+	// atomically replaces the file, so it is safe to check without a lock
+	if !s.damaged() {
+		return nil, nil
+	}
+
+	fileLock, err := b.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer fileLock.Unlock()
+	return s.recover()
+}
+
+// parseEntry converts a saved entry to a report entry, parsing the
+// title and marker from its task with parseSavedTask()
+// If the task can't be parsed, it returns the error along with an entry
+// that uses the whole task as its title
+func (b *Backend) parseEntry(e SavedEntry) (*ReportEntry, error) {
 	entry := &ReportEntry{
-		Title: matches[1],
+		ID:       e.ID,
+		Billable: e.Billable,
+		Category: e.Category,
+		End:      e.End,
+		Notes:    e.Notes,
+		Project:  e.Project,
+		Tags:     e.Tags,
+		Task:     e.Task,
+		Title:    e.Task,
+		Ts:       e.End,
+	}
+	task, err := parseSavedTask(e.Task, b.markers())
+	if err == nil {
+		entry.Title = task.Title
+		if entry.Category == "" {
+			entry.Category = task.Category
+		}
 	}
-	if matches[2] == "**" {
-		entry.Brk = true
+	// The category field takes precedence over markers in the task
+	if entry.Category == "" {
+		return entry, err
 	}
-	if matches[2] == "***" {
-		entry.Ignore = true
+	c, ok := b.category(entry.Category)
+	if !ok {
+		if err == nil {
+			err = errors.Errorf("unknown category %q", entry.Category)
+		}
+		return entry, err
 	}
-	return entry, nil
+	entry.Brk = c.Kind == KindBreak
+	entry.Ignore = c.Kind == KindIgnore
+	entry.ClassNames = []string{c.ClassName}
+	entry.Color = c.Color
+	return entry, err
 }
 
 // Create an instance of the structures that operate on Omw data
-func Create(fp *os.File, omwDir, omwFile string) *Backend {
-	return &Backend{
-		ctx: context.Background(),
+// The timesheet is stored in omwFile using NewStore unless an
+// option provides a different Store.  Every file, including the lock,
+// is opened on the OS file system unless WithFs provides another one.
+func Create(fp *os.File, omwDir, omwFile string, opts ...Option) *Backend {
+	editor := DefaultEditor
+	if preferredEditor := os.Getenv("EDITOR"); preferredEditor != "" {
+		editor = preferredEditor
+	}
+	b := &Backend{
+		clock: systemClock{},
 		config: &config{
-			omwDir:  omwDir,
-			omwFile: omwFile,
+			omwDir:      omwDir,
+			omwFile:     omwFile,
+			editor:      editor,
+			lockFile:    fmt.Sprintf("%s.lock", omwFile),
+			journalFile: fmt.Sprintf("%s.journal", omwFile),
+			lockTimeout: DefaultLockTimeout,
+			categories:  mergeCategories(nil),
+			weekStart:   time.Monday,
+			account:     DefaultAccount,
 		},
 		fp:     fp,
+		fs:     afero.NewOsFs(),
+		locks:  &lockState{},
 		worker: nil,
 	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	if b.store == nil {
+		b.store = NewStore(b.fs, omwFile)
+	}
+	return b
+}
+
+// WithDayStart sets the time of day, as an offset from midnight,
+// when a new day starts in reports
+func WithDayStart(d time.Duration) Option {
+	return func(b *Backend) {
+		b.config.dayStart = d
+	}
+}
+
+// WithEditor sets the command that omw edit runs to edit the timesheet
+// The default is the EDITOR environment variable or DefaultEditor
+func WithEditor(editor string) Option {
+	return func(b *Backend) {
+		b.config.editor = editor
+	}
+}
+
+// WithTerminal runs the editor inside a new terminal window, ie: xterm
+// It is ignored on Windows
+func WithTerminal(term string) Option {
+	return func(b *Backend) {
+		b.config.omwTerm = term
+	}
+}
+
+// WithWeekStart sets the first day of the week in reports
+// The default is Monday
+func WithWeekStart(day time.Weekday) Option {
+	return func(b *Backend) {
+		b.config.weekStart = day
+	}
+}
+
+// WithLockTimeout sets how long to wait for another omw process
+// to release the timesheet before giving up
+func WithLockTimeout(d time.Duration) Option {
+	return func(b *Backend) {
+		b.config.lockTimeout = d
+	}
+}
+
+// WithAccount sets the ledger account of entries without a project in
+// timeclock exports, the default is DefaultAccount
+func WithAccount(account string) Option {
+	return func(b *Backend) {
+		b.config.account = account
+	}
+}
+
+// WithJiraProjects sets the project keys of the Jira issue keys in task
+// titles, so other words that look like one, ie: UTF-8, are left alone.
+// By default any key that isn't a well-known abbreviation matches.
+func WithJiraProjects(keys ...string) Option {
+	return func(b *Backend) {
+		b.config.jiraProjects = keys
+	}
+}
+
+// WithStore saves the timesheet to s instead of the default store for omwFile
+func WithStore(s Store) Option {
+	return func(b *Backend) {
+		b.store = s
+	}
 }
 
 // runCommand Executes cmd and handles any output
@@ -520,10 +962,10 @@ func runCommand(cmd *exec.Cmd) error {
 //
 // It does not:
 // 1. Check for in-order task times
-func validateEdit(fn string) (*SavedItems, error) {
+func validateEdit(fs afero.Fs, fn string) (*SavedItems, error) {
 	keys := make(map[string]bool)
 	data := SavedItems{}
-	r, err := ioutil.ReadFile(fn)
+	r, err := afero.ReadFile(fs, fn)
 	if err != nil {
 		return nil, errors.Wrap(err, "reading temporary file")
 	}
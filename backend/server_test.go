@@ -8,16 +8,19 @@ import (
 	"os/exec"
 	"reflect"
 	"testing"
+	"time"
+
+	"github.com/spf13/afero"
 )
 
 func TestBackend_Add(t *testing.T) {
 	type fields struct {
-		ctx    context.Context
 		config *config
 		fp     *os.File
 		worker *worker
 	}
 	type args struct {
+		ctx  context.Context
 		args []string
 	}
 	tests := []struct {
@@ -30,19 +33,17 @@ func TestBackend_Add(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			b := &Backend{
-				ctx:    tt.fields.ctx,
 				config: tt.fields.config,
 				fp:     tt.fields.fp,
 				worker: tt.fields.worker,
 			}
-			b.Add(tt.args.args)
+			b.Add(tt.args.ctx, tt.args.args)
 		})
 	}
 }
 
 func TestBackend_Close(t *testing.T) {
 	type fields struct {
-		ctx    context.Context
 		config *config
 		fp     *os.File
 		worker *worker
@@ -57,7 +58,6 @@ func TestBackend_Close(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			b := &Backend{
-				ctx:    tt.fields.ctx,
 				config: tt.fields.config,
 				fp:     tt.fields.fp,
 				worker: tt.fields.worker,
@@ -93,14 +93,17 @@ func TestCreate(t *testing.T) {
 
 func TestBackend_Edit(t *testing.T) {
 	type fields struct {
-		ctx    context.Context
 		config *config
 		fp     *os.File
 		worker *worker
 	}
+	type args struct {
+		ctx context.Context
+	}
 	tests := []struct {
 		name    string
 		fields  fields
+		args    args
 		wantErr bool
 	}{
 		// TODO: Add test cases.
@@ -108,12 +111,11 @@ func TestBackend_Edit(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			b := &Backend{
-				ctx:    tt.fields.ctx,
 				config: tt.fields.config,
 				fp:     tt.fields.fp,
 				worker: tt.fields.worker,
 			}
-			if _, err := b.Edit(); (err != nil) != tt.wantErr {
+			if _, err := b.Edit(tt.args.ctx); (err != nil) != tt.wantErr {
 				t.Errorf("Backend.Edit() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -122,38 +124,40 @@ func TestBackend_Edit(t *testing.T) {
 
 func TestBackend_Hello(t *testing.T) {
 	type fields struct {
-		ctx    context.Context
 		config *config
 		fp     *os.File
 		worker *worker
 	}
+	type args struct {
+		ctx context.Context
+	}
 	tests := []struct {
 		name   string
 		fields fields
+		args   args
 	}{
 		// TODO: Add test cases.
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			b := &Backend{
-				ctx:    tt.fields.ctx,
 				config: tt.fields.config,
 				fp:     tt.fields.fp,
 				worker: tt.fields.worker,
 			}
-			b.Hello()
+			b.Hello(tt.args.ctx)
 		})
 	}
 }
 
 func TestBackend_Report(t *testing.T) {
 	type fields struct {
-		ctx    context.Context
 		config *config
 		fp     *os.File
 		worker *worker
 	}
 	type args struct {
+		ctx   context.Context
 		start string
 		end   string
 	}
@@ -168,26 +172,276 @@ func TestBackend_Report(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			b := &Backend{
-				ctx:    tt.fields.ctx,
 				config: tt.fields.config,
 				fp:     tt.fields.fp,
 				worker: tt.fields.worker,
 			}
-			b.Report(tt.args.start, tt.args.end, "text")
+			b.Report(tt.args.ctx, tt.args.start, tt.args.end, "text")
 		})
 	}
 }
 
+// testClock is a Clock that only moves when a test sets it
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func TestBackend_clockAndFs(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.Local)
+	clock := &testClock{now: day.Add(9 * time.Hour)}
+	fs := afero.NewMemMapFs()
+	fs.MkdirAll("/omw", 0755)
+	b := Create(nil, "/omw", "/omw/omw.toml", WithFs(fs), WithClock(clock), WithLockTimeout(50*time.Millisecond))
+
+	steps := []struct {
+		at  time.Duration
+		add func() error
+	}{
+		{9 * time.Hour, func() error { return b.Hello(ctx) }},
+		{10 * time.Hour, func() error { return b.Add(ctx, []string{"email"}) }},
+		{11 * time.Hour, func() error { return b.Stretch(ctx) }},
+		{11*time.Hour + 30*time.Minute, func() error { return b.Add(ctx, []string{"lunch", "**"}) }},
+	}
+	for _, step := range steps {
+		clock.now = day.Add(step.at)
+		if err := step.add(); err != nil {
+			t.Fatalf("adding entry at %s error = %v", step.at, err)
+		}
+	}
+
+	clock.now = day.Add(17 * time.Hour)
+	report, err := b.BuildReport(ctx, "today", "today")
+	if err != nil {
+		t.Fatalf("BuildReport() error = %v", err)
+	}
+	if !report.From.Equal(day) || report.TaskHrs != 2*time.Hour || report.BrkHrs != 30*time.Minute {
+		t.Errorf("BuildReport() from %v, task %v, break %v, want %v, 2h0m0s, 30m0s", report.From, report.TaskHrs, report.BrkHrs, day)
+	}
+	if _, err := os.Stat("/omw/omw.toml"); !os.IsNotExist(err) {
+		t.Errorf("timesheet written to the OS file system, Stat() error = %v", err)
+	}
+
+	fileLock, err := b.lock(ctx)
+	if err != nil {
+		t.Fatalf("lock() error = %v", err)
+	}
+	if _, err := b.Entries(ctx); err == nil {
+		t.Errorf("Entries() while locked error = nil, want timeout")
+	}
+	fileLock.Unlock()
+	if _, err := b.Entries(ctx); err != nil {
+		t.Errorf("Entries() after unlock error = %v", err)
+	}
+}
+
+func TestBackend_Add_at(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	entries := []SavedEntry{
+		{ID: "a", End: day.Add(9 * time.Hour), Task: "hello"},
+		{ID: "b", End: day.Add(11 * time.Hour), Task: "review"},
+		{ID: "c", End: day.Add(12 * time.Hour), Task: "lunch **"},
+	}
+	b, cleanup := newTestBackend(t, entries, WithClock(&testClock{now: day.Add(13 * time.Hour)}))
+	defer cleanup()
+
+	if err := b.Add(ctx, []string{"email"}, EntryAt(day.Add(10*time.Hour))); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := b.Stretch(ctx, EntryAt(day.Add(11*time.Hour+30*time.Minute))); err != nil {
+		t.Fatalf("Stretch() error = %v", err)
+	}
+	if err := b.Hello(ctx, EntryAt(day.Add(-24*time.Hour))); err != nil {
+		t.Fatalf("Hello() error = %v", err)
+	}
+	if err := b.Add(ctx, []string{"write"}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	got, err := b.Entries(ctx)
+	if err != nil {
+		t.Fatalf("Entries() error = %v", err)
+	}
+	want := []struct {
+		task string
+		end  time.Duration
+	}{
+		{"hello", -24 * time.Hour},
+		{"hello", 9 * time.Hour},
+		{"email", 10 * time.Hour},
+		{"review", 11 * time.Hour},
+		{"review", 11*time.Hour + 30*time.Minute},
+		{"lunch **", 12 * time.Hour},
+		{"write", 13 * time.Hour},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Entries() = %v, want %d entries", got, len(want))
+	}
+	for i, w := range want {
+		if got[i].Task != w.task || !got[i].End.Equal(day.Add(w.end)) {
+			t.Errorf("Entries()[%d] = %s at %v, want %s at %v", i, got[i].Task, got[i].End, w.task, day.Add(w.end))
+		}
+	}
+}
+
+func TestBackend_Add_projectAndTags(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	b, cleanup := newTestBackend(t, nil, WithClock(&testClock{now: day.Add(10 * time.Hour)}))
+	defer cleanup()
+
+	tests := []struct {
+		name string
+		args []string
+		opts []EntryOption
+		want SavedEntry
+	}{
+		{"words", []string{"review", "PR", "+omw", "#code", "#review"}, nil,
+			SavedEntry{Task: "review PR", Project: "omw", Tags: []string{"code", "review"}}},
+		{"quoted", []string{"+omw review PR #code"}, nil,
+			SavedEntry{Task: "review PR", Project: "omw", Tags: []string{"code"}}},
+		{"with flags", []string{"call #sales"}, []EntryOption{EntryProject("acme"), EntryTags("phone"), EntryBillable(true)},
+			SavedEntry{Task: "call", Project: "acme", Tags: []string{"sales", "phone"}, Billable: true}},
+		{"break", []string{"lunch", "#food", "**"}, nil,
+			SavedEntry{Task: "lunch **", Tags: []string{"food"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := b.Add(ctx, tt.args, tt.opts...); err != nil {
+				t.Fatalf("Add() error = %v", err)
+			}
+			entry, err := b.FindEntry(ctx, "last")
+			if err != nil {
+				t.Fatalf("FindEntry() error = %v", err)
+			}
+			got := *entry
+			got.ID, got.End = "", time.Time{}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Add() saved %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	if err := b.Add(ctx, []string{"work", "+a", "+b"}); err == nil {
+		t.Error("Add() with two projects error = nil")
+	}
+}
+
+func TestBackend_Add_escaped(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	b, cleanup := newTestBackend(t, []SavedEntry{{ID: "a", End: day.Add(9 * time.Hour), Task: "hello"}},
+		WithClock(&testClock{now: day.Add(10 * time.Hour)}),
+		WithCategories(Category{Name: "meeting", Marker: "@@", Kind: KindTask}))
+	defer cleanup()
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantTask string
+		want     Task
+	}{
+		{"sigils", []string{`\#hashtag`, "meeting", `\+notaproject`, "+omw"},
+			`\#hashtag meeting \+notaproject`, Task{Title: "#hashtag meeting +notaproject", Project: "omw"}},
+		{"marker", []string{"lunch", `\**`}, `lunch \**`, Task{Title: "lunch **"}},
+		{"attached marker", []string{`\lunch**`, "***"}, `\lunch** ***`,
+			Task{Title: "lunch**", Category: "ignore", Marker: "***"}},
+		{"user-defined marker", []string{"standup", `\@@`}, `standup \@@`, Task{Title: "standup @@"}},
+		{"backslash", []string{`\\server`, "share"}, `\\server share`, Task{Title: `\server share`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := b.Add(ctx, tt.args); err != nil {
+				t.Fatalf("Add() error = %v", err)
+			}
+			saved, err := b.Entries(ctx)
+			if err != nil {
+				t.Fatalf("Entries() error = %v", err)
+			}
+			last := saved[len(saved)-1]
+			if last.Task != tt.wantTask {
+				t.Errorf("Add() saved %q, want %q", last.Task, tt.wantTask)
+			}
+			got, err := parseTask(last.Task, b.markers())
+			if err != nil {
+				t.Fatalf("parseTask() error = %v", err)
+			}
+			got.Project = last.Project
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("parseTask() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+
+	if _, err := b.RetitleEntry(ctx, "last", []string{"read", `\#hashtags`, "**"}); err != nil {
+		t.Fatalf("RetitleEntry() error = %v", err)
+	}
+	entry, err := b.FindEntry(ctx, "last")
+	if err != nil {
+		t.Fatalf("FindEntry() error = %v", err)
+	}
+	if want := `read \#hashtags **`; entry.Task != want {
+		t.Errorf("RetitleEntry() saved %q, want %q", entry.Task, want)
+	}
+}
+
+func TestBackend_Report_savedTasks(t *testing.T) {
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	b, cleanup := newTestBackend(t, []SavedEntry{
+		{ID: "a", End: day.Add(9 * time.Hour), Task: "hello"},
+		// saved before tasks had projects and tags
+		{ID: "b", End: day.Add(10 * time.Hour), Task: "fix #1234 for #acme +web"},
+		{ID: "c", End: day.Add(11 * time.Hour), Task: `read \#hashtags`, Project: "omw", Tags: []string{"docs"}},
+		{ID: "d", End: day.Add(12 * time.Hour), Task: "lunch **"},
+	})
+	defer cleanup()
+	report, err := b.BuildReport(context.Background(), "2020-01-02", "2020-01-02", GroupBy(GroupTag))
+	if err != nil {
+		t.Fatalf("BuildReport() error = %v", err)
+	}
+	type parsed struct {
+		Title   string
+		Project string
+		Tags    []string
+		Brk     bool
+	}
+	got := []parsed{}
+	for _, e := range report.Entries[1:] {
+		got = append(got, parsed{e.Title, e.Project, e.Tags, e.Brk})
+	}
+	want := []parsed{
+		{"fix #1234 for #acme +web", "", nil, false},
+		{"read #hashtags", "omw", []string{"docs"}, false},
+		{"lunch", "", nil, true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("BuildReport() entries = %+v, want %+v", got, want)
+	}
+	tags := []string{}
+	for _, g := range report.Groups {
+		tags = append(tags, g.Key)
+	}
+	if want := []string{noGroupKey, "docs"}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("BuildReport() tag groups = %v, want %v", tags, want)
+	}
+}
+
 func TestBackend_Stretch(t *testing.T) {
 	type fields struct {
-		ctx    context.Context
 		config *config
 		fp     *os.File
 		worker *worker
 	}
+	type args struct {
+		ctx context.Context
+	}
 	tests := []struct {
 		name    string
 		fields  fields
+		args    args
 		wantErr bool
 	}{
 		// TODO: Add test cases.
@@ -195,12 +449,11 @@ func TestBackend_Stretch(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			b := &Backend{
-				ctx:    tt.fields.ctx,
 				config: tt.fields.config,
 				fp:     tt.fields.fp,
 				worker: tt.fields.worker,
 			}
-			if err := b.Stretch(); (err != nil) != tt.wantErr {
+			if err := b.Stretch(tt.args.ctx); (err != nil) != tt.wantErr {
 				t.Errorf("Backend.Stretch() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -209,13 +462,13 @@ func TestBackend_Stretch(t *testing.T) {
 
 func TestBackend_addEntry(t *testing.T) {
 	type fields struct {
-		ctx    context.Context
 		config *config
 		fp     *os.File
 		worker *worker
 	}
 	type args struct {
-		s string
+		ctx context.Context
+		s   string
 	}
 	tests := []struct {
 		name   string
@@ -227,12 +480,11 @@ func TestBackend_addEntry(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			b := &Backend{
-				ctx:    tt.fields.ctx,
 				config: tt.fields.config,
 				fp:     tt.fields.fp,
 				worker: tt.fields.worker,
 			}
-			b.addEntry(tt.args.s)
+			b.addEntry(tt.args.ctx, OpAdd, tt.args.s)
 		})
 	}
 }
@@ -0,0 +1,53 @@
+package backend
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// Store persists the entries of a timesheet
+// Implementations do not need to lock against other processes,
+// Backend holds a file lock around every call that modifies the store.
+type Store interface {
+	// Append adds entries after the last entry in the timesheet
+	Append(entries ...SavedEntry) error
+	// List returns the entries with an end time between from and to,
+	// inclusive, in timesheet order.  A zero from or to leaves that side
+	// of the range open.
+	List(from, to time.Time) ([]SavedEntry, error)
+	// Get returns the entry with the given ID or ErrNotFound
+	Get(id string) (*SavedEntry, error)
+	// Update replaces the entry that has the same ID as entry or returns ErrNotFound
+	Update(entry SavedEntry) error
+	// Delete removes the entry with the given ID or returns ErrNotFound
+	Delete(id string) error
+	// ReplaceAll replaces the entire timesheet with entries
+	ReplaceAll(entries []SavedEntry) error
+}
+
+// NewStore returns the Store implementation that matches the extension of
+// path.  Files ending in .db or .bolt use the embedded key-value store,
+// everything else is a TOML file on fs.  bbolt memory-maps the database,
+// so it is always opened on the OS file system.
+func NewStore(fs afero.Fs, path string) Store {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".db", ".bolt":
+		return NewBoltStore(path)
+	}
+	return NewTOMLStore(fs, path)
+}
+
+// inRange returns true if t is between from and to, inclusive
+// A zero from or to leaves that side of the range open
+func inRange(t, from, to time.Time) bool {
+	if !from.IsZero() && t.Before(from) {
+		return false
+	}
+	if !to.IsZero() && t.After(to) {
+		return false
+	}
+	return true
+}
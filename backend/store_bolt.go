@@ -0,0 +1,204 @@
+package backend
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// entriesBucket maps a time-ordered key to a JSON encoded SavedEntry
+	entriesBucket = []byte("entries")
+	// idsBucket maps an entry ID to its key in entriesBucket
+	idsBucket = []byte("ids")
+)
+
+// boltOpenTimeout limits how long we wait for another process
+// that has the database open
+const boltOpenTimeout = 5 * time.Second
+
+// boltStore keeps the timesheet in an embedded bbolt key-value database
+// Entries are indexed by end time, so List only reads the requested range.
+// The database is only held open for the length of each call, because
+// bbolt keeps an exclusive lock on the file while it is open.
+type boltStore struct {
+	path string
+}
+
+// NewBoltStore returns a Store that saves entries to the bbolt database at path
+func NewBoltStore(path string) Store {
+	return &boltStore{path: path}
+}
+
+func (s *boltStore) Append(entries ...SavedEntry) error {
+	return s.update(func(tx *bolt.Tx) error {
+		for _, e := range entries {
+			err := putEntry(tx, e)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *boltStore) List(from, to time.Time) ([]SavedEntry, error) {
+	entries := []SavedEntry{}
+	err := s.view(func(tx *bolt.Tx) error {
+		c := tx.Bucket(entriesBucket).Cursor()
+		var k, v []byte
+		if from.IsZero() {
+			k, v = c.First()
+		} else {
+			k, v = c.Seek(timeKey(from))
+		}
+		for ; k != nil; k, v = c.Next() {
+			e := SavedEntry{}
+			err := json.Unmarshal(v, &e)
+			if err != nil {
+				return errors.Wrapf(err, "can't unmarshal entry %x", k)
+			}
+			if !to.IsZero() && e.End.After(to) {
+				break
+			}
+			entries = append(entries, e)
+		}
+		return nil
+	})
+	return entries, err
+}
+
+func (s *boltStore) Get(id string) (*SavedEntry, error) {
+	e := SavedEntry{}
+	err := s.view(func(tx *bolt.Tx) error {
+		key := tx.Bucket(idsBucket).Get([]byte(id))
+		if key == nil {
+			return ErrNotFound
+		}
+		return json.Unmarshal(tx.Bucket(entriesBucket).Get(key), &e)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (s *boltStore) Update(entry SavedEntry) error {
+	return s.update(func(tx *bolt.Tx) error {
+		err := deleteEntry(tx, entry.ID)
+		if err != nil {
+			return err
+		}
+		return putEntry(tx, entry)
+	})
+}
+
+func (s *boltStore) Delete(id string) error {
+	return s.update(func(tx *bolt.Tx) error {
+		return deleteEntry(tx, id)
+	})
+}
+
+func (s *boltStore) ReplaceAll(entries []SavedEntry) error {
+	return s.update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{entriesBucket, idsBucket} {
+			err := tx.DeleteBucket(name)
+			if err != nil {
+				return err
+			}
+			_, err = tx.CreateBucket(name)
+			if err != nil {
+				return err
+			}
+		}
+		for _, e := range entries {
+			err := putEntry(tx, e)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// open opens the database and makes sure both buckets exist
+func (s *boltStore) open() (*bolt.DB, error) {
+	db, err := bolt.Open(s.path, 0644, &bolt.Options{Timeout: boltOpenTimeout})
+	if err != nil {
+		return nil, errors.Wrapf(err, "can't open %s", s.path)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{entriesBucket, idsBucket} {
+			_, err := tx.CreateBucketIfNotExists(name)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+func (s *boltStore) update(fn func(tx *bolt.Tx) error) error {
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.Update(fn)
+}
+
+func (s *boltStore) view(fn func(tx *bolt.Tx) error) error {
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.View(fn)
+}
+
+// putEntry saves e under its time-ordered key and indexes its ID
+// Entries with the same end time are ordered by ID.
+func putEntry(tx *bolt.Tx, e SavedEntry) error {
+	if tx.Bucket(idsBucket).Get([]byte(e.ID)) != nil {
+		return errors.Errorf("duplicate entry ID %s", e.ID)
+	}
+	v, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, "can't marshal entry")
+	}
+	key := append(timeKey(e.End), []byte(e.ID)...)
+	err = tx.Bucket(entriesBucket).Put(key, v)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(idsBucket).Put([]byte(e.ID), key)
+}
+
+func deleteEntry(tx *bolt.Tx, id string) error {
+	ids := tx.Bucket(idsBucket)
+	key := ids.Get([]byte(id))
+	if key == nil {
+		return ErrNotFound
+	}
+	err := tx.Bucket(entriesBucket).Delete(key)
+	if err != nil {
+		return err
+	}
+	return ids.Delete([]byte(id))
+}
+
+// timeKey encodes t so that keys sort in time order, including
+// times before 1970
+func timeKey(t time.Time) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano())^(1<<63))
+	return key
+}
@@ -0,0 +1,178 @@
+package backend
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+func TestStore(t *testing.T) {
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	entries := []SavedEntry{
+		{ID: "a", End: day.Add(9 * time.Hour), Task: "hello"},
+		{ID: "b", End: day.Add(10 * time.Hour), Task: "email"},
+		{ID: "c", End: day.Add(12 * time.Hour), Task: "lunch **"},
+	}
+	tests := []struct {
+		name string
+		file string
+	}{
+		{"toml", "omw.toml"},
+		{"bolt", "omw.db"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "omw")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, tt.file)
+			ioutil.WriteFile(path, nil, 0644)
+			s := NewStore(afero.NewOsFs(), path)
+
+			if err := s.Append(entries...); err != nil {
+				t.Fatalf("Append() error = %v", err)
+			}
+			got, err := s.List(time.Time{}, time.Time{})
+			if err != nil || !reflect.DeepEqual(got, entries) {
+				t.Errorf("List() = %v, %v, want %v", got, err, entries)
+			}
+			got, err = s.List(day.Add(10*time.Hour), day.Add(11*time.Hour))
+			if err != nil || !reflect.DeepEqual(got, entries[1:2]) {
+				t.Errorf("List(range) = %v, %v, want %v", got, err, entries[1:2])
+			}
+
+			updated := SavedEntry{ID: "b", End: day.Add(11 * time.Hour), Task: "meeting"}
+			if err := s.Update(updated); err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			if e, err := s.Get("b"); err != nil || !reflect.DeepEqual(*e, updated) {
+				t.Errorf("Get() = %v, %v, want %v", e, err, updated)
+			}
+			if err := s.Delete("a"); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			if _, err := s.Get("a"); err != ErrNotFound {
+				t.Errorf("Get() after Delete error = %v, want %v", err, ErrNotFound)
+			}
+			if err := s.Delete("a"); err != ErrNotFound {
+				t.Errorf("Delete() missing error = %v, want %v", err, ErrNotFound)
+			}
+
+			if err := s.ReplaceAll(entries[:1]); err != nil {
+				t.Fatalf("ReplaceAll() error = %v", err)
+			}
+			got, err = s.List(time.Time{}, time.Time{})
+			if err != nil || !reflect.DeepEqual(got, entries[:1]) {
+				t.Errorf("List() after ReplaceAll = %v, %v, want %v", got, err, entries[:1])
+			}
+		})
+	}
+}
+
+func TestTOMLStore_roundTrip(t *testing.T) {
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	// written by omw before entries had a project, tags, notes, billable
+	// flag or category
+	old := "\n[[entries]]\n  end = 2020-01-02T09:00:00Z\n  id = \"a\"\n  task = \"hello\"\n\n" +
+		"[[entries]]\n  end = 2020-01-02T10:00:00Z\n  id = \"b\"\n  task = \"lunch **\"\n"
+	full := SavedEntry{ID: "c", End: day.Add(11 * time.Hour), Task: "review", Project: "omw",
+		Tags: []string{"code", "pr"}, Notes: "two files", Billable: true, Category: "break"}
+
+	dir, err := ioutil.TempDir("", "omw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "omw.toml")
+	ioutil.WriteFile(path, []byte(old), 0644)
+	s := &tomlStore{fs: afero.NewOsFs(), path: path}
+
+	got, err := s.List(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	want := []SavedEntry{
+		{ID: "a", End: day.Add(9 * time.Hour), Task: "hello"},
+		{ID: "b", End: day.Add(10 * time.Hour), Task: "lunch **"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("List() = %+v, want %+v", got, want)
+	}
+	if err := s.ReplaceAll(got); err != nil {
+		t.Fatalf("ReplaceAll() error = %v", err)
+	}
+	if saved, _ := ioutil.ReadFile(path); string(saved) != old {
+		t.Errorf("ReplaceAll() saved %q, want the old file %q", saved, old)
+	}
+
+	if err := s.Append(full); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	saved, _ := ioutil.ReadFile(path)
+	if !strings.HasPrefix(string(saved), old) {
+		t.Errorf("Append() changed the old entries: %q", saved)
+	}
+	got, err = s.List(time.Time{}, time.Time{})
+	if err != nil || !reflect.DeepEqual(got, append(want, full)) {
+		t.Errorf("List() after Append = %+v, %v, want %+v", got, err, append(want, full))
+	}
+}
+
+func TestTOMLStore_recover(t *testing.T) {
+	complete := "[[entries]]\n  end = 2020-01-02T09:00:00Z\n  id = \"a\"\n  task = \"hello\"\n"
+	tests := []struct {
+		name        string
+		contents    string
+		want        string
+		wantRecover bool
+		wantErr     bool
+	}{
+		{"empty", "", "", false, false},
+		{"complete", complete, complete, false, false},
+		{"torn value", complete + "\n[[entries]]\n  end = 2020-01-02T1", complete + "\n", true, false},
+		{"missing id", complete + "\n[[entries]]\n  end = 2020-01-02T10:00:00Z\n", complete + "\n", true, false},
+		{"empty task", complete + "\n[[entries]]\n  end = 2020-01-02T10:00:00Z\n  id = \"b\"\n  task = \"\"\n",
+			complete + "\n[[entries]]\n  end = 2020-01-02T10:00:00Z\n  id = \"b\"\n  task = \"\"\n", false, false},
+		{"no task", complete + "\n[[entries]]\n  end = 2020-01-02T10:00:00Z\n  id = \"b\"\n",
+			complete + "\n[[entries]]\n  end = 2020-01-02T10:00:00Z\n  id = \"b\"\n", false, false},
+		{"damaged middle", "[[entries]]\n  end = 2020-01\n" + complete, "", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "omw")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, "omw.toml")
+			ioutil.WriteFile(path, []byte(tt.contents), 0644)
+			s := &tomlStore{fs: afero.NewOsFs(), path: path}
+
+			recovery, err := s.recover()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("recover() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (recovery != nil) != tt.wantRecover {
+				t.Errorf("recover() = %+v, wantRecover %v", recovery, tt.wantRecover)
+			}
+			if recovery != nil && !strings.HasPrefix(recovery.Removed, entriesHeader) {
+				t.Errorf("recover() removed %q, want the last entry", recovery.Removed)
+			}
+			if tt.wantErr {
+				return
+			}
+			got, _ := ioutil.ReadFile(path)
+			if string(got) != tt.want {
+				t.Errorf("recover() left %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package backend
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pelletier/go-toml"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+// entriesHeader starts every entry in the TOML file
+const entriesHeader = "[[entries]]"
+
+// tomlStore keeps the timesheet in a single human-editable TOML file
+// Every change, including appending new entries, atomically replaces
+// the whole file so a crash can't leave a partially written entry.
+type tomlStore struct {
+	fs   afero.Fs
+	path string
+}
+
+// NewTOMLStore returns a Store that saves entries to the TOML file at path on fs
+func NewTOMLStore(fs afero.Fs, path string) Store {
+	return &tomlStore{fs: fs, path: path}
+}
+
+// Append keeps the existing file contents as-is and adds the new entries
+// after them
+func (s *tomlStore) Append(entries ...SavedEntry) error {
+	current, err := s.raw()
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "can't read %s", s.path)
+	}
+	data := SavedItems{Entries: entries}
+	entriesBytes, err := toml.Marshal(data)
+	if err != nil {
+		return errors.Wrap(err, "can't marshal data")
+	}
+	if len(current) > 0 && !bytes.HasSuffix(current, []byte("\n")) {
+		current = append(current, '\n')
+	}
+	err = writeFileAtomic(s.fs, s.path, append(current, entriesBytes...), 0644)
+	if err != nil {
+		return errors.Wrap(err, "error saving new data")
+	}
+	return nil
+}
+
+func (s *tomlStore) List(from, to time.Time) ([]SavedEntry, error) {
+	data, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	if from.IsZero() && to.IsZero() {
+		return data.Entries, nil
+	}
+	entries := []SavedEntry{}
+	for _, e := range data.Entries {
+		if inRange(e.End, from, to) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+func (s *tomlStore) Get(id string) (*SavedEntry, error) {
+	data, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range data.Entries {
+		if e.ID == id {
+			return &e, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *tomlStore) Update(entry SavedEntry) error {
+	data, err := s.read()
+	if err != nil {
+		return err
+	}
+	for i, e := range data.Entries {
+		if e.ID == entry.ID {
+			data.Entries[i] = entry
+			return s.ReplaceAll(data.Entries)
+		}
+	}
+	return ErrNotFound
+}
+
+func (s *tomlStore) Delete(id string) error {
+	data, err := s.read()
+	if err != nil {
+		return err
+	}
+	for i, e := range data.Entries {
+		if e.ID == id {
+			data.Entries = append(data.Entries[:i], data.Entries[i+1:]...)
+			return s.ReplaceAll(data.Entries)
+		}
+	}
+	return ErrNotFound
+}
+
+// ReplaceAll atomically replaces the timesheet with entries
+func (s *tomlStore) ReplaceAll(entries []SavedEntry) error {
+	dataBytes, err := toml.Marshal(SavedItems{Entries: entries})
+	if err != nil {
+		return errors.Wrap(err, "can't marshal data")
+	}
+	return writeFileAtomic(s.fs, s.path, dataBytes, 0644)
+}
+
+// recover truncates a torn entry at the end of the file, left by a crash
+// during a non-atomic append in an older version of omw or another program.
+// The original file is kept with a .corrupt extension.
+// Corruption anywhere else in the file is reported but never changed.
+func (s *tomlStore) recover() (*Recovery, error) {
+	current, err := s.raw()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "can't read %s", s.path)
+	}
+	if !isDamaged(current) {
+		return nil, nil
+	}
+
+	last := bytes.LastIndex(current, []byte(entriesHeader))
+	if last < 0 {
+		return nil, errors.Errorf("can't recover %s - no complete entries found", s.path)
+	}
+	truncated := current[:last]
+	data := SavedItems{}
+	inner := toml.Unmarshal(truncated, &data)
+	if inner != nil {
+		return nil, errors.Wrapf(inner, "%s is damaged before the last entry - fix it with omw edit or restore %s.bak", s.path, s.path)
+	}
+
+	corrupt := fmt.Sprintf("%s.corrupt", s.path)
+	err = writeFileAtomic(s.fs, corrupt, current, 0644)
+	if err != nil {
+		return nil, errors.Wrap(err, "saving damaged file")
+	}
+	err = writeFileAtomic(s.fs, s.path, truncated, 0644)
+	if err != nil {
+		return nil, errors.Wrap(err, "truncating damaged entry")
+	}
+	return &Recovery{Path: s.path, Damaged: corrupt, Removed: string(current[last:])}, nil
+}
+
+// damaged returns true if the timesheet needs to be recovered
+func (s *tomlStore) damaged() bool {
+	current, err := s.raw()
+	return err == nil && isDamaged(current)
+}
+
+// isDamaged returns true if data can't be unmarshaled or the last
+// entry is torn
+func isDamaged(data []byte) bool {
+	items := SavedItems{}
+	err := toml.Unmarshal(data, &items)
+	if err != nil {
+		return true
+	}
+	return len(items.Entries) > 0 && !isComplete(items.Entries[len(items.Entries)-1])
+}
+
+// raw returns the timesheet file exactly as it is saved on disk
+func (s *tomlStore) raw() ([]byte, error) {
+	return afero.ReadFile(s.fs, s.path)
+}
+
+// isComplete returns true if e has the fields that identify an entry
+// Every other field, even the task, may be empty in a valid entry.
+func isComplete(e SavedEntry) bool {
+	return e.ID != "" && !e.End.IsZero()
+}
+
+// read returns the entries in the timesheet, which has none until the
+// first entry is appended
+func (s *tomlStore) read() (*SavedItems, error) {
+	r, err := s.raw()
+	if os.IsNotExist(err) {
+		return &SavedItems{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "can't read data file")
+	}
+	data := SavedItems{}
+	err = toml.Unmarshal(r, &data)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't unmarshal data")
+	}
+	return &data, nil
+}
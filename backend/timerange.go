@@ -0,0 +1,369 @@
+package backend
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Period is a named, repeating time period, like a pay period
+// Start is the first day of any one of the periods and Length is a
+// number of days, weeks, months or years, ie: 14d, 2w, 1m or 1y.
+// A period called pay-period can be used in a time range as pay-period,
+// current-pay-period, last-pay-period or next-pay-period.
+type Period struct {
+	Name   string    `json:"name"`
+	Start  time.Time `json:"start"`
+	Length string    `json:"length"`
+}
+
+var (
+	relativeRe = regexp.MustCompile(`^-(\d+)([hdw])$`)
+	weekRe     = regexp.MustCompile(`^(\d{4})-w(\d{1,2})$`)
+	quarterRe  = regexp.MustCompile(`^(\d{4})-q([1-4])$`)
+	monthRe    = regexp.MustCompile(`^(\d{4})-(\d{1,2})$`)
+	yearRe     = regexp.MustCompile(`^(\d{4})$`)
+	lengthRe   = regexp.MustCompile(`^(\d+)([dwmy])$`)
+)
+
+// builtinPeriods can't be used as the name of a Period
+var builtinPeriods = []string{"day", "week", "month", "quarter", "year"}
+
+// periodOffsets are the words that select a period relative to the
+// current one, ie: last week
+var periodOffsets = map[string]int{
+	"this":     0,
+	"current":  0,
+	"last":     -1,
+	"previous": -1,
+	"next":     1,
+}
+
+// instantLayouts are the layouts of a single point in time
+var instantLayouts = []string{
+	"2006-1-2 15:04",
+	"2006-1-2 15:04:05",
+	"2006-1-2T15:04",
+	"2006-1-2T15:04:05",
+}
+
+// ValidatePeriods checks named periods before they are passed to WithPeriods
+func ValidatePeriods(periods []Period) error {
+	names := map[string]bool{}
+	for _, p := range periods {
+		name := strings.ToLower(p.Name)
+		if name == "" || strings.ContainsAny(name, " \t") {
+			return errors.Errorf("invalid period name %q", p.Name)
+		}
+		for _, builtin := range builtinPeriods {
+			if name == builtin {
+				return errors.Errorf("period name %q is reserved", p.Name)
+			}
+		}
+		if names[name] {
+			return errors.Errorf("period %q is defined more than once", p.Name)
+		}
+		names[name] = true
+		if p.Start.IsZero() {
+			return errors.Errorf("period %q is missing a start date", p.Name)
+		}
+		if _, _, err := parseLength(p.Length); err != nil {
+			return errors.Wrapf(err, "period %q", p.Name)
+		}
+	}
+	return nil
+}
+
+// WithPeriods adds named periods, like a pay period, that can be used
+// in time ranges.  Check periods with ValidatePeriods first.
+func WithPeriods(periods ...Period) Option {
+	return func(b *Backend) {
+		b.config.periods = periods
+	}
+}
+
+// ParseRange returns the time range from the start of from to the end of to
+//
+// Both from and to can be:
+//
+//	2020-01-02                  a day
+//	2020-01-02 13:00            a point in time, also 2020-01-02T13:00 or RFC3339
+//	now                         the current time, see WithClock
+//	today, yesterday, tomorrow  a day
+//	this week, last month       the current, last or next day, week, month,
+//	next year                   quarter, year or named period (see Period)
+//	2020-W03                    an ISO week, which always starts on Monday
+//	2020-Q1, 2020-01, 2020      a quarter, month or year
+//	-7d, -2w                    the day 7 days or 2 weeks ago
+//	-3h                         3 hours ago
+//
+// Days start at the day start time, see WithDayStart, and weeks start on
+// the day set with WithWeekStart.
+func (b *Backend) ParseRange(from, to string) (time.Time, time.Time, error) {
+	now := b.now()
+	start, _, err := b.parseInterval(from, now)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.Wrap(err, "can't parse start of range")
+	}
+	_, end, err := b.parseInterval(to, now)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.Wrap(err, "can't parse end of range")
+	}
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, errors.Errorf("range ends (%s) before it starts (%s)", end.Format(time.RFC3339), start.Format(time.RFC3339))
+	}
+	return start, end, nil
+}
+
+// ParseTime returns the point in time s, ie: the end time of an entry
+// added with EntryAt
+//
+//	14:30              the last time it was 14:30, today or yesterday
+//	yesterday 17:05    a time of day on a day, see ParseRange
+//	now, -3h           the current time or 3 hours ago
+//	2020-01-02 13:00   a date and time, also 2020-01-02T13:00 or RFC3339
+//
+// A time of day before the day start time (see WithDayStart) is in the
+// night after the day, so "yesterday 02:00" is early this morning with a
+// 4am day start.
+func (b *Backend) ParseTime(s string) (time.Time, error) {
+	now := b.now()
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return time.Time{}, errors.New("missing time")
+	}
+	if clock, ok := parseClock(fields[len(fields)-1]); ok {
+		if len(fields) == 1 {
+			t := atClock(now, clock)
+			if t.After(now) {
+				t = atClock(now.AddDate(0, 0, -1), clock)
+			}
+			return t, nil
+		}
+		day := strings.Join(fields[:len(fields)-1], " ")
+		start, _, err := b.parseInterval(day, now)
+		if err != nil {
+			return time.Time{}, errors.Wrapf(err, "can't parse day %q", day)
+		}
+		d := b.dayOf(start)
+		if clock < b.config.dayStart {
+			d = nextDay(d)
+		}
+		return atClock(d, clock), nil
+	}
+	start, end, err := b.parseInterval(s, now)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !start.Equal(end) {
+		return time.Time{}, errors.Errorf("%q is a range of time - add a time of day, ie: \"%s 14:30\"", s, s)
+	}
+	return start, nil
+}
+
+// Ago returns the time d before now, by the Backend clock like the end
+// time of a new entry
+func (b *Backend) Ago(d time.Duration) time.Time {
+	return b.now().Add(-d)
+}
+
+// parseClock parses a time of day, ie: 14:30 or 14:30:15, into the time
+// since midnight
+func parseClock(s string) (time.Duration, bool) {
+	for _, layout := range []string{"15:04", "15:04:05"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second, true
+		}
+	}
+	return 0, false
+}
+
+// atClock returns the time of day clock on the day of t
+func atClock(t time.Time, clock time.Duration) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, int(clock/time.Second), 0, t.Location())
+}
+
+// parseInterval returns the start and end of the time range expression s
+func (b *Backend) parseInterval(s string, now time.Time) (time.Time, time.Time, error) {
+	loc := now.Location()
+	expr := strings.ToLower(strings.Join(strings.Fields(s), " "))
+	today := b.dayOf(now)
+
+	if expr == "now" {
+		return now, now, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, t, nil
+	}
+	for _, layout := range instantLayouts {
+		if t, err := time.ParseInLocation(layout, strings.TrimSpace(s), loc); err == nil {
+			return t, t, nil
+		}
+	}
+	if t, err := time.ParseInLocation("2006-1-2", expr, loc); err == nil {
+		return b.days(t, nextDay(t))
+	}
+
+	if m := weekRe.FindStringSubmatch(expr); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		week, _ := strconv.Atoi(m[2])
+		// December 28th is always in the last week of the year
+		_, weeks := time.Date(year, time.December, 28, 0, 0, 0, 0, loc).ISOWeek()
+		if week < 1 || week > weeks {
+			return time.Time{}, time.Time{}, errors.Errorf("invalid week in %q", s)
+		}
+		// week 1 is the week with January 4th in it
+		jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, loc)
+		monday := jan4.AddDate(0, 0, -((int(jan4.Weekday())+6)%7)+(week-1)*7)
+		return b.days(monday, monday.AddDate(0, 0, 7))
+	}
+	if m := quarterRe.FindStringSubmatch(expr); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		quarter, _ := strconv.Atoi(m[2])
+		first := time.Date(year, time.Month(quarter*3-2), 1, 0, 0, 0, 0, loc)
+		return b.days(first, first.AddDate(0, 3, 0))
+	}
+	if m := monthRe.FindStringSubmatch(expr); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		month, _ := strconv.Atoi(m[2])
+		if month < 1 || month > 12 {
+			return time.Time{}, time.Time{}, errors.Errorf("invalid month in %q", s)
+		}
+		first := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, loc)
+		return b.days(first, first.AddDate(0, 1, 0))
+	}
+	if m := yearRe.FindStringSubmatch(expr); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		first := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+		return b.days(first, first.AddDate(1, 0, 0))
+	}
+	if m := relativeRe.FindStringSubmatch(expr); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		switch m[2] {
+		case "h":
+			t := now.Add(-time.Duration(n) * time.Hour)
+			return t, t, nil
+		case "w":
+			n *= 7
+		}
+		day := today.AddDate(0, 0, -n)
+		return b.days(day, nextDay(day))
+	}
+
+	switch expr {
+	case "today":
+		return b.days(today, nextDay(today))
+	case "yesterday":
+		day := today.AddDate(0, 0, -1)
+		return b.days(day, nextDay(day))
+	case "tomorrow":
+		day := nextDay(today)
+		return b.days(day, nextDay(day))
+	}
+
+	// this week, last-month, next pay-period, ...
+	offset, unit := 0, expr
+	for word, n := range periodOffsets {
+		if strings.HasPrefix(expr, word+" ") || strings.HasPrefix(expr, word+"-") {
+			offset, unit = n, expr[len(word)+1:]
+			break
+		}
+	}
+	first, last, ok := b.period(unit, today, offset)
+	if !ok {
+		return time.Time{}, time.Time{}, errors.Errorf("unknown time range %q", s)
+	}
+	return b.days(first, last)
+}
+
+// period returns the first day of the period called unit that today is
+// in, moved by offset periods, and the first day after it
+func (b *Backend) period(unit string, today time.Time, offset int) (time.Time, time.Time, bool) {
+	switch unit {
+	case "day":
+		day := today.AddDate(0, 0, offset)
+		return day, nextDay(day), true
+	case "week":
+		first := today.AddDate(0, 0, -((int(today.Weekday())-int(b.config.weekStart)+7)%7)+offset*7)
+		return first, first.AddDate(0, 0, 7), true
+	case "month":
+		first := time.Date(today.Year(), today.Month()+time.Month(offset), 1, 0, 0, 0, 0, today.Location())
+		return first, first.AddDate(0, 1, 0), true
+	case "quarter":
+		month := (int(today.Month())-1)/3*3 + 1 + offset*3
+		first := time.Date(today.Year(), time.Month(month), 1, 0, 0, 0, 0, today.Location())
+		return first, first.AddDate(0, 3, 0), true
+	case "year":
+		first := time.Date(today.Year()+offset, time.January, 1, 0, 0, 0, 0, today.Location())
+		return first, first.AddDate(1, 0, 0), true
+	}
+
+	for _, p := range b.config.periods {
+		if strings.ToLower(p.Name) != unit {
+			continue
+		}
+		days, months, err := parseLength(p.Length)
+		if err != nil {
+			return time.Time{}, time.Time{}, false
+		}
+		anchor := time.Date(p.Start.Year(), p.Start.Month(), p.Start.Day(), 0, 0, 0, 0, today.Location())
+		var n int
+		if days > 0 {
+			n = floorDiv(daysBetween(anchor, today), days) + offset
+			first := anchor.AddDate(0, 0, n*days)
+			return first, first.AddDate(0, 0, days), true
+		}
+		elapsed := (today.Year()-anchor.Year())*12 + int(today.Month()-anchor.Month())
+		if today.Day() < anchor.Day() {
+			elapsed--
+		}
+		n = floorDiv(elapsed, months) + offset
+		first := anchor.AddDate(0, n*months, 0)
+		return first, anchor.AddDate(0, (n+1)*months, 0), true
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+// days returns the time range from the start of day first to the start
+// of day last
+func (b *Backend) days(first, last time.Time) (time.Time, time.Time, error) {
+	return b.dayBoundary(first), b.dayBoundary(last), nil
+}
+
+// parseLength parses the length of a Period into days or months
+func parseLength(s string) (int, int, error) {
+	m := lengthRe.FindStringSubmatch(strings.ToLower(s))
+	if m == nil {
+		return 0, 0, errors.Errorf("invalid length %q - use a number of days, weeks, months or years, ie: 14d, 2w, 1m or 1y", s)
+	}
+	n, _ := strconv.Atoi(m[1])
+	if n == 0 {
+		return 0, 0, errors.Errorf("invalid length %q", s)
+	}
+	switch m[2] {
+	case "w":
+		return n * 7, 0, nil
+	case "m":
+		return 0, n, nil
+	case "y":
+		return 0, n * 12, nil
+	}
+	return n, 0, nil
+}
+
+// daysBetween returns the number of calendar days from a to b
+func daysBetween(a, b time.Time) int {
+	ua := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	ub := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(ub.Sub(ua).Hours() / 24)
+}
+
+func floorDiv(a, b int) int {
+	if a < 0 {
+		return -((-a + b - 1) / b)
+	}
+	return a / b
+}
@@ -0,0 +1,158 @@
+package backend
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackend_parseInterval(t *testing.T) {
+	// Thursday
+	now := time.Date(2020, 1, 16, 15, 30, 0, 0, time.UTC)
+	date := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+	b := &Backend{config: &config{
+		weekStart: time.Monday,
+		periods:   []Period{{Name: "pay-period", Start: date(2020, 1, 6), Length: "2w"}},
+	}}
+	tests := []struct {
+		name      string
+		s         string
+		wantStart time.Time
+		wantEnd   time.Time
+		wantErr   bool
+	}{
+		{"date", "2020-1-2", date(2020, 1, 2), date(2020, 1, 3), false},
+		{"date time", "2020-01-02 13:00", date(2020, 1, 2).Add(13 * time.Hour), date(2020, 1, 2).Add(13 * time.Hour), false},
+		{"rfc3339", "2020-01-02T13:00:00Z", date(2020, 1, 2).Add(13 * time.Hour), date(2020, 1, 2).Add(13 * time.Hour), false},
+		{"now", "now", now, now, false},
+		{"today", "today", date(2020, 1, 16), date(2020, 1, 17), false},
+		{"yesterday", "Yesterday", date(2020, 1, 15), date(2020, 1, 16), false},
+		{"this week", "this week", date(2020, 1, 13), date(2020, 1, 20), false},
+		{"last week", "last-week", date(2020, 1, 6), date(2020, 1, 13), false},
+		{"last month", "last month", date(2019, 12, 1), date(2020, 1, 1), false},
+		{"next quarter", "next quarter", date(2020, 4, 1), date(2020, 7, 1), false},
+		{"this year", "this year", date(2020, 1, 1), date(2021, 1, 1), false},
+		{"iso week", "2020-W03", date(2020, 1, 13), date(2020, 1, 20), false},
+		{"iso week 1", "2021-w01", date(2021, 1, 4), date(2021, 1, 11), false},
+		{"quarter", "2020-Q1", date(2020, 1, 1), date(2020, 4, 1), false},
+		{"month", "2020-02", date(2020, 2, 1), date(2020, 3, 1), false},
+		{"year", "2019", date(2019, 1, 1), date(2020, 1, 1), false},
+		{"days ago", "-7d", date(2020, 1, 9), date(2020, 1, 10), false},
+		{"weeks ago", "-2w", date(2020, 1, 2), date(2020, 1, 3), false},
+		{"hours ago", "-3h", now.Add(-3 * time.Hour), now.Add(-3 * time.Hour), false},
+		{"pay period", "current-pay-period", date(2020, 1, 6), date(2020, 1, 20), false},
+		{"last pay period", "last pay-period", date(2019, 12, 23), date(2020, 1, 6), false},
+		{"unknown", "someday", time.Time{}, time.Time{}, true},
+		{"invalid week", "2020-W54", time.Time{}, time.Time{}, true},
+		{"week 53", "2020-W53", date(2020, 12, 28), date(2021, 1, 4), false},
+		{"no week 53", "2021-W53", time.Time{}, time.Time{}, true},
+		{"invalid month", "2020-13", time.Time{}, time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, err := b.parseInterval(tt.s, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseInterval() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !start.Equal(tt.wantStart) || !end.Equal(tt.wantEnd) {
+				t.Errorf("parseInterval() = %v, %v, want %v, %v", start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestBackend_parseInterval_dayStart(t *testing.T) {
+	now := time.Date(2020, 1, 16, 2, 0, 0, 0, time.UTC)
+	b := &Backend{config: &config{dayStart: 4 * time.Hour}}
+	start, end, err := b.parseInterval("today", now)
+	wantStart := time.Date(2020, 1, 15, 4, 0, 0, 0, time.UTC)
+	if err != nil || !start.Equal(wantStart) || !end.Equal(wantStart.Add(24*time.Hour)) {
+		t.Errorf("parseInterval() = %v, %v, %v, want %v, %v", start, end, err, wantStart, wantStart.Add(24*time.Hour))
+	}
+}
+
+func TestValidatePeriods(t *testing.T) {
+	start := time.Date(2020, 1, 6, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		periods []Period
+		wantErr bool
+	}{
+		{"none", nil, false},
+		{"pay period", []Period{{Name: "pay-period", Start: start, Length: "14d"}}, false},
+		{"reserved name", []Period{{Name: "week", Start: start, Length: "1w"}}, true},
+		{"space in name", []Period{{Name: "pay period", Start: start, Length: "1w"}}, true},
+		{"duplicate", []Period{{Name: "a", Start: start, Length: "1m"}, {Name: "A", Start: start, Length: "1m"}}, true},
+		{"missing start", []Period{{Name: "a", Length: "1m"}}, true},
+		{"invalid length", []Period{{Name: "a", Start: start, Length: "2 weeks"}}, true},
+		{"zero length", []Period{{Name: "a", Start: start, Length: "0d"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidatePeriods(tt.periods); (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePeriods() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBackend_ParseTime(t *testing.T) {
+	// Thursday
+	now := time.Date(2020, 1, 16, 15, 30, 0, 0, time.UTC)
+	at := func(d, h, m int) time.Time {
+		return time.Date(2020, 1, d, h, m, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name     string
+		dayStart time.Duration
+		s        string
+		want     time.Time
+		wantErr  bool
+	}{
+		{"time today", 0, "14:30", at(16, 14, 30), false},
+		{"time yesterday", 0, "17:05", at(15, 17, 5), false},
+		{"seconds", 0, "9:15:30", at(16, 9, 15).Add(30 * time.Second), false},
+		{"day and time", 0, "yesterday 17:05", at(15, 17, 5), false},
+		{"date and time", 0, "2020-01-02 13:00", at(2, 13, 0), false},
+		{"night after day start", 4 * time.Hour, "yesterday 2:00", at(16, 2, 0), false},
+		{"now", 0, "now", now, false},
+		{"hours ago", 0, "-3h", now.Add(-3 * time.Hour), false},
+		{"range", 0, "yesterday", time.Time{}, true},
+		{"empty", 0, " ", time.Time{}, true},
+		{"unknown day", 0, "someday 9:00", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Backend{clock: &testClock{now: now}, config: &config{dayStart: tt.dayStart, weekStart: time.Monday}}
+			got, err := b.ParseTime(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTime() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackend_Ago(t *testing.T) {
+	now := time.Date(2020, 1, 16, 15, 30, 0, 0, time.UTC)
+	b := &Backend{clock: &testClock{now: now}, config: &config{}}
+	tests := []struct {
+		name string
+		d    time.Duration
+		want time.Time
+	}{
+		{"now", 0, now},
+		{"minutes", 20 * time.Minute, time.Date(2020, 1, 16, 15, 10, 0, 0, time.UTC)},
+		{"yesterday", 16 * time.Hour, time.Date(2020, 1, 15, 23, 30, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := b.Ago(tt.d); !got.Equal(tt.want) {
+				t.Errorf("Ago() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
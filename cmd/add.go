@@ -17,10 +17,34 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"time"
 
+	"github.com/mcdafydd/omw/backend"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
+// Project is the project of the new entry
+var Project string
+
+// Tags are the tags of the new entry
+var Tags []string
+
+// Notes are free-form notes saved with the new entry
+var Notes string
+
+// Billable marks the new entry as billable time
+var Billable bool
+
+// Category is the category of the new entry
+var Category string
+
+// At is the end time of the new entry, ie: 14:30 or "yesterday 17:05"
+var At string
+
+// Ago sets the end time of the new entry to a duration before now
+var Ago time.Duration
+
 // addCmd represents the add command
 var addCmd = &cobra.Command{
 	Use:   "add",
@@ -28,22 +52,80 @@ var addCmd = &cobra.Command{
 	Long: `Add <task> should be run at the end of a task before switching focus.
 	Add '**' after your task to categorize it as break time (ie: lunch)
 	Add '***' after your task to categorize it as time to ignore (ie: commuting)
+	Add '+project' to set the project and '#tag' to add tags - quote the task
+	when it has tags, since most shells treat # as the start of a comment
+	Tasks may use any language or emoji.  Projects and tags must start with
+	a letter, use a backslash to keep a word like \#hashtag in the title.
+	Use --at or --ago when you forgot to add a task when you switched - the
+	task is inserted in chronological order.
 	`,
 	Example: `
 	omw add finish meeting with team
 	omw add break **
 	omw add commuting ***
+	omw add "review pull request +omw #code #review"
+	omw add call with customer --project acme --billable --notes "renewal"
+	omw add code review --at 14:30
+	omw add "standup +omw" --at "yesterday 9:15"
+	omw add coffee ** --ago 20m
 	`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if len(args) == 0 {
 			fmt.Fprintf(os.Stderr, "Missing task after add command!\n")
 			os.Exit(1)
 		}
-		server.Add(args)
-		return nil
+		opts, err := entryTime()
+		if err != nil {
+			return err
+		}
+		opts = append(opts,
+			backend.EntryTags(Tags...),
+			backend.EntryBillable(Billable),
+		)
+		if Project != "" {
+			opts = append(opts, backend.EntryProject(Project))
+		}
+		if Notes != "" {
+			opts = append(opts, backend.EntryNotes(Notes))
+		}
+		if Category != "" {
+			opts = append(opts, backend.EntryCategory(Category))
+		}
+		return server.Add(ctx, args, opts...)
 	},
 }
 
 func init() {
+	addCmd.Flags().StringVarP(&Project, "project", "p", "", "Project of the task, same as +project")
+	addCmd.Flags().StringSliceVar(&Tags, "tag", nil, "Tag the task, same as #tag - may be repeated")
+	addCmd.Flags().StringVarP(&Notes, "notes", "n", "", "Notes saved with the task")
+	addCmd.Flags().BoolVarP(&Billable, "billable", "b", false, "Mark the task as billable")
+	addCmd.Flags().StringVarP(&Category, "category", "c", "", "Category of the task - break, ignore or one defined in your config file")
+	addTimeFlags(addCmd)
 	rootCmd.AddCommand(addCmd)
 }
+
+// addTimeFlags adds the --at and --ago flags, which set the time of a new entry
+func addTimeFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&At, "at", "", "Time of the entry instead of now, ie: 14:30, \"yesterday 17:05\" or \"2020-01-02 13:00\"")
+	cmd.Flags().DurationVar(&Ago, "ago", 0, "Time of the entry as a duration before now, ie: 20m or 1h30m")
+}
+
+// entryTime returns the EntryAt option for the --at or --ago flag
+func entryTime() ([]backend.EntryOption, error) {
+	switch {
+	case At != "" && Ago != 0:
+		return nil, errors.New("use either --at or --ago")
+	case At != "":
+		t, err := server.ParseTime(At)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid --at")
+		}
+		return []backend.EntryOption{backend.EntryAt(t)}, nil
+	case Ago < 0:
+		return nil, errors.Errorf("invalid --ago %s - it must be positive", Ago)
+	case Ago > 0:
+		return []backend.EntryOption{backend.EntryAt(server.Ago(Ago))}, nil
+	}
+	return nil, nil
+}
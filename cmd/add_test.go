@@ -0,0 +1,62 @@
+// Copyright © 2019 David McPike
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mcdafydd/omw/backend"
+	"github.com/spf13/afero"
+)
+
+func Test_entryTime(t *testing.T) {
+	saved := server
+	defer func() { server, At, Ago = saved, "", 0 }()
+	server = backend.Create(nil, "/omw", "/omw/omw.toml", backend.WithFs(afero.NewMemMapFs()), backend.WithClock(testClock{}))
+	now := testClock{}.Now()
+
+	tests := []struct {
+		name    string
+		at      string
+		ago     time.Duration
+		want    time.Time
+		wantErr bool
+	}{
+		{"now", "", 0, time.Time{}, false},
+		{"at", "11:15", 0, time.Date(2020, 1, 8, 11, 15, 0, 0, time.UTC), false},
+		{"ago", "", 20 * time.Minute, now.Add(-20 * time.Minute), false},
+		{"ago yesterday", "", 13 * time.Hour, time.Date(2020, 1, 7, 23, 0, 0, 0, time.UTC), false},
+		{"both", "11:15", time.Minute, time.Time{}, true},
+		{"negative ago", "", -time.Minute, time.Time{}, true},
+		{"invalid at", "noonish", 0, time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			At, Ago = tt.at, tt.ago
+			opts, err := entryTime()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("entryTime() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			entry := backend.SavedEntry{}
+			for _, opt := range opts {
+				opt(&entry)
+			}
+			if !entry.End.Equal(tt.want) {
+				t.Errorf("entryTime() end = %v, want %v", entry.End, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright © 2019 David McPike
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/mcdafydd/omw/backend"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// The fields that amend sets, separate from the flags of omw add
+var (
+	// AmendProject is the new project of the entry
+	AmendProject string
+	// AmendTags are the tags that amend adds to the entry
+	AmendTags []string
+	// Untag are the tags that amend removes from the entry
+	Untag []string
+	// AmendNotes are the new notes of the entry
+	AmendNotes string
+	// AmendBillable is the new billable flag of the entry
+	AmendBillable bool
+	// AmendCategory is the new category of the entry
+	AmendCategory string
+)
+
+// shortIDLength is how much of an entry ID is shown after it is changed
+const shortIDLength = 8
+
+// refHelp describes the ways to refer to an entry
+const refHelp = `
+	An entry is an entry ID or the start of one, "last" for the last entry
+	or a position from the end of the timesheet, like -2 for the entry
+	before the last one.  Put -- after any flags and before a position, so
+	it isn't read as a flag: omw amend --billable -- -2
+	The timesheet is saved to omw.toml.bak before it is changed.`
+
+// amendCmd represents the amend command
+var amendCmd = &cobra.Command{
+	Use:   "amend <entry>",
+	Short: "Change the project, tags, notes, billable flag or category of an entry",
+	Long: `Amend changes the fields of a single entry that you set with the
+	flags of omw add, without opening the whole timesheet in an editor.
+	Use omw retitle to change the task and omw move to change the time.
+	` + refHelp,
+	Example: `
+	omw amend last --project acme --billable
+	omw amend 3f2a --tag review --untag code
+	omw amend --notes "called back" --category meeting -- -2
+	`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := []backend.EntryOption{}
+		flags := cmd.Flags()
+		if flags.Changed("project") {
+			opts = append(opts, backend.EntryProject(AmendProject))
+		}
+		if flags.Changed("tag") {
+			opts = append(opts, backend.EntryTags(AmendTags...))
+		}
+		if flags.Changed("untag") {
+			opts = append(opts, backend.EntryUntag(Untag...))
+		}
+		if flags.Changed("notes") {
+			opts = append(opts, backend.EntryNotes(AmendNotes))
+		}
+		if flags.Changed("billable") {
+			opts = append(opts, backend.EntryBillable(AmendBillable))
+		}
+		if flags.Changed("category") {
+			opts = append(opts, backend.EntryCategory(AmendCategory))
+		}
+		if len(opts) == 0 {
+			return errors.New("nothing to change - use --project, --tag, --untag, --notes, --billable or --category")
+		}
+		entry, err := server.AmendEntry(ctx, args[0], opts...)
+		if err != nil {
+			return err
+		}
+		printEntry("Amended", *entry)
+		return nil
+	},
+}
+
+func init() {
+	amendCmd.Flags().StringVarP(&AmendProject, "project", "p", "", "Project of the task, empty to remove it")
+	amendCmd.Flags().StringSliceVar(&AmendTags, "tag", nil, "Add a tag - may be repeated")
+	amendCmd.Flags().StringSliceVar(&Untag, "untag", nil, "Remove a tag - may be repeated")
+	amendCmd.Flags().StringVarP(&AmendNotes, "notes", "n", "", "Notes saved with the task, empty to remove them")
+	amendCmd.Flags().BoolVarP(&AmendBillable, "billable", "b", false, "Mark the task as billable, or not with --billable=false")
+	amendCmd.Flags().StringVarP(&AmendCategory, "category", "c", "", "Category of the task, empty to remove it")
+	rootCmd.AddCommand(amendCmd)
+}
+
+// printEntry shows an entry that a command changed, with a short ID that
+// the entry commands accept
+func printEntry(verb string, e backend.SavedEntry) {
+	id := e.ID
+	if len(id) > shortIDLength {
+		id = id[:shortIDLength]
+	}
+	fmt.Printf("%s %s %s %s\n", verb, id, e.End.Local().Format("2006-01-02 15:04"), e.Task)
+}
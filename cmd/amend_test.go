@@ -0,0 +1,41 @@
+// Copyright © 2019 David McPike
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func Test_amendFlags(t *testing.T) {
+	flags := amendCmd.Flags()
+	defer func() {
+		AmendProject, AmendTags, AmendNotes, AmendBillable, AmendCategory = "", nil, "", false, ""
+		flags.VisitAll(func(f *pflag.Flag) { f.Changed = false })
+	}()
+	err := flags.Parse([]string{"--project", "acme", "--tag", "review", "--notes", "called back", "--billable", "--category", "break"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if AmendProject != "acme" || !reflect.DeepEqual(AmendTags, []string{"review"}) || AmendNotes != "called back" || !AmendBillable || AmendCategory != "break" {
+		t.Errorf("amend flags = %q %v %q %v %q", AmendProject, AmendTags, AmendNotes, AmendBillable, AmendCategory)
+	}
+	// the flags of omw add are untouched
+	if Project != "" || len(Tags) != 0 || Notes != "" || Billable || Category != "" {
+		t.Errorf("add flags = %q %v %q %v %q, want them unset", Project, Tags, Notes, Billable, Category)
+	}
+}
@@ -0,0 +1,274 @@
+// Copyright © 2019 David McPike
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mcdafydd/omw/backend"
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// Config file keys - every key can also be set with an OMW_ environment
+// variable, ie: OMW_DATA_DIR or OMW_REPORT_FORMAT
+const (
+	keyDataDir       = "data_dir"
+	keyDataFile      = "data_file"
+	keyEditor        = "editor"
+	keyTerminal      = "terminal"
+	keyLockTimeout   = "lock_timeout"
+	keyDayStart      = "day_start"
+	keyDayHello      = "day_start_hello"
+	keyWeekStart     = "week_start"
+	keyCategories    = "categories"
+	keyPeriods       = "periods"
+	keyReportFmt     = "report.format"
+	keyReportGroupBy = "report.group_by"
+	keyRound         = "report.round"
+	keyRoundMode     = "report.round_mode"
+	keyRoundScope    = "report.round_scope"
+	keyCarryOver     = "report.carry_over"
+	keyAccount       = "timeclock.account"
+	keyJiraProjects  = "jira.projects"
+)
+
+// setConfigDefaults sets the defaults and environment variables for
+// each config key
+func setConfigDefaults() error {
+	dataDir, err := defaultDataDir()
+	if err != nil {
+		return err
+	}
+	viper.SetDefault(keyDataDir, dataDir)
+	viper.SetDefault(keyDataFile, DefaultFile)
+	viper.SetDefault(keyLockTimeout, backend.DefaultLockTimeout.String())
+	viper.SetDefault(keyDayStart, "00:00")
+	viper.SetDefault(keyWeekStart, "monday")
+	viper.SetDefault(keyReportFmt, "text")
+	viper.SetDefault(keyRoundMode, backend.RoundNearest)
+	viper.SetDefault(keyRoundScope, backend.RoundEntry)
+	viper.SetDefault(keyAccount, backend.DefaultAccount)
+
+	viper.SetEnvPrefix("omw")
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
+	// older and conventional names for the same settings - viper only
+	// reads one variable for each key, so bind the first one that is set
+	for key, envs := range map[string][]string{
+		keyDataFile: {"OMW_DATA_FILE", "OMW_FILE"},
+		keyEditor:   {"OMW_EDITOR", "EDITOR"},
+		keyTerminal: {"OMW_TERMINAL", "OMW_TERM"},
+	} {
+		env := envs[0]
+		for _, name := range envs {
+			if _, ok := os.LookupEnv(name); ok {
+				env = name
+				break
+			}
+		}
+		err = viper.BindEnv(key, env)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// defaultDataDir is $XDG_DATA_HOME/omw, or ~/.local/share/omw if
+// XDG_DATA_HOME is not set
+func defaultDataDir() (string, error) {
+	if dataHome := os.Getenv("XDG_DATA_HOME"); dataHome != "" {
+		return filepath.Join(dataHome, "omw"), nil
+	}
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", errors.Wrap(err, "can't find home directory")
+	}
+	return filepath.Join(home, DefaultDir), nil
+}
+
+// dataPaths returns the data directory and data file from the config,
+// a relative data file is inside the data directory
+func dataPaths() (string, string, error) {
+	omwDir, err := homedir.Expand(viper.GetString(keyDataDir))
+	if err != nil {
+		return "", "", errors.Wrapf(err, "invalid %s", keyDataDir)
+	}
+	omwFile, err := homedir.Expand(viper.GetString(keyDataFile))
+	if err != nil {
+		return "", "", errors.Wrapf(err, "invalid %s", keyDataFile)
+	}
+	if !filepath.IsAbs(omwFile) {
+		omwFile = filepath.Join(omwDir, omwFile)
+	}
+	return omwDir, omwFile, nil
+}
+
+// backendOptions turns the config into backend options
+func backendOptions() ([]backend.Option, error) {
+	opts := []backend.Option{}
+
+	if editor := viper.GetString(keyEditor); editor != "" {
+		opts = append(opts, backend.WithEditor(editor))
+	}
+	if term := viper.GetString(keyTerminal); term != "" {
+		opts = append(opts, backend.WithTerminal(term))
+	}
+
+	lockTimeout, err := time.ParseDuration(viper.GetString(keyLockTimeout))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid %s", keyLockTimeout)
+	}
+	opts = append(opts, backend.WithLockTimeout(lockTimeout))
+
+	dayStart, err := parseDayStart(viper.GetString(keyDayStart))
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, backend.WithDayStart(dayStart))
+	opts = append(opts, backend.WithDayStartHello(viper.GetBool(keyDayHello)))
+
+	weekStart, err := parseWeekday(viper.GetString(keyWeekStart))
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, backend.WithWeekStart(weekStart))
+
+	categories, err := readCategories()
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid categories")
+	}
+	opts = append(opts, backend.WithCategories(categories...))
+
+	periods, err := readPeriods()
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid periods")
+	}
+	opts = append(opts, backend.WithPeriods(periods...))
+	opts = append(opts, backend.WithAccount(viper.GetString(keyAccount)))
+	opts = append(opts, backend.WithJiraProjects(viper.GetStringSlice(keyJiraProjects)...))
+
+	return opts, nil
+}
+
+// parseDayStart parses the time of day when a new day starts, ie: 04:00
+func parseDayStart(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, errors.Errorf("invalid %s %q - use HH:MM, ie: 04:00", keyDayStart, s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// parseWeekday parses an English weekday name, ie: monday or Sun
+func parseWeekday(s string) (time.Weekday, error) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := day.String()
+		if strings.EqualFold(s, name) || strings.EqualFold(s, name[:3]) {
+			return day, nil
+		}
+	}
+	return 0, errors.Errorf("invalid %s %q - use a day of the week, ie: monday", keyWeekStart, s)
+}
+
+// categoryConfig is a [[categories]] table in the config file
+type categoryConfig struct {
+	Name    string `mapstructure:"name"`
+	Marker  string `mapstructure:"marker"`
+	Kind    string `mapstructure:"kind"`
+	Class   string `mapstructure:"class"`
+	Color   string `mapstructure:"color"`
+	Account string `mapstructure:"account"`
+}
+
+// readCategories reads the user-defined categories from the config file
+func readCategories() ([]backend.Category, error) {
+	configs := []categoryConfig{}
+	err := viper.UnmarshalKey(keyCategories, &configs)
+	if err != nil {
+		return nil, err
+	}
+	categories := []backend.Category{}
+	for _, c := range configs {
+		categories = append(categories, backend.Category{
+			Name:      c.Name,
+			Marker:    c.Marker,
+			Kind:      c.Kind,
+			ClassName: c.Class,
+			Color:     c.Color,
+			Account:   c.Account,
+		})
+	}
+	return categories, backend.ValidateCategories(categories)
+}
+
+// periodConfig is a [[periods]] table in the config file
+type periodConfig struct {
+	Name   string      `mapstructure:"name"`
+	Start  interface{} `mapstructure:"start"`
+	Length string      `mapstructure:"length"`
+}
+
+// readPeriods reads the named periods from the config file
+func readPeriods() ([]backend.Period, error) {
+	configs := []periodConfig{}
+	err := viper.UnmarshalKey(keyPeriods, &configs)
+	if err != nil {
+		return nil, err
+	}
+	periods := []backend.Period{}
+	for _, c := range configs {
+		// start can be a TOML date or a string
+		start, err := time.ParseInLocation("2006-1-2", fmt.Sprint(c.Start), time.Local)
+		if t, ok := c.Start.(time.Time); ok {
+			start, err = t, nil
+		}
+		if err != nil {
+			return nil, errors.Errorf("period %q has invalid start %v - use YYYY-MM-DD", c.Name, c.Start)
+		}
+		periods = append(periods, backend.Period{
+			Name:   c.Name,
+			Start:  start,
+			Length: c.Length,
+		})
+	}
+	return periods, backend.ValidatePeriods(periods)
+}
+
+// reportRounding reads the rounding of reports from the config and flags,
+// or returns nil if durations aren't rounded
+func reportRounding() (*backend.Rounding, error) {
+	increment := viper.GetString(keyRound)
+	if increment == "" || increment == "0" {
+		return nil, nil
+	}
+	d, err := time.ParseDuration(increment)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid rounding increment %q", increment)
+	}
+	r := &backend.Rounding{
+		Increment: d,
+		Mode:      strings.ToLower(viper.GetString(keyRoundMode)),
+		Scope:     strings.ToLower(viper.GetString(keyRoundScope)),
+		CarryOver: viper.GetBool(keyCarryOver),
+	}
+	return r, r.Validate()
+}
@@ -0,0 +1,205 @@
+// Copyright © 2019 David McPike
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mcdafydd/omw/backend"
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+)
+
+// resetConfig starts a test case with a new viper, the given environment
+// and config file contents, like initConfig does.  The returned function
+// restores the environment.
+func resetConfig(t *testing.T, env map[string]string, config string) func() {
+	viper.Reset()
+	viper.BindPFlag(keyLockTimeout, rootCmd.PersistentFlags().Lookup("lock-timeout"))
+	saved := map[string]*string{}
+	for _, key := range []string{"XDG_DATA_HOME", "OMW_DATA_DIR", "OMW_DATA_FILE", "OMW_FILE",
+		"OMW_LOCK_TIMEOUT", "OMW_DAY_START", "OMW_WEEK_START", "OMW_REPORT_FORMAT",
+		"OMW_EDITOR", "EDITOR", "OMW_TERMINAL", "OMW_TERM"} {
+		if value, ok := os.LookupEnv(key); ok {
+			saved[key] = &value
+		} else {
+			saved[key] = nil
+		}
+		os.Unsetenv(key)
+	}
+	for key, value := range env {
+		os.Setenv(key, value)
+	}
+	if err := setConfigDefaults(); err != nil {
+		t.Fatal(err)
+	}
+	if config != "" {
+		viper.SetConfigType("toml")
+		if err := viper.ReadConfig(strings.NewReader(config)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return func() {
+		for key, value := range saved {
+			if value == nil {
+				os.Unsetenv(key)
+			} else {
+				os.Setenv(key, *value)
+			}
+		}
+		viper.Reset()
+		viper.BindPFlag(keyLockTimeout, rootCmd.PersistentFlags().Lookup("lock-timeout"))
+	}
+}
+
+func Test_dataPaths(t *testing.T) {
+	home, err := homedir.Dir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		env      map[string]string
+		config   string
+		wantDir  string
+		wantFile string
+	}{
+		{"default", nil, "",
+			filepath.Join(home, DefaultDir), filepath.Join(home, DefaultDir, DefaultFile)},
+		{"XDG_DATA_HOME", map[string]string{"XDG_DATA_HOME": "/xdg"}, "",
+			"/xdg/omw", "/xdg/omw/omw.toml"},
+		{"config", nil, "data_dir = \"~/timesheets\"\ndata_file = \"work.toml\"\n",
+			filepath.Join(home, "timesheets"), filepath.Join(home, "timesheets", "work.toml")},
+		{"absolute file", nil, "data_file = \"/tmp/omw.db\"\n",
+			filepath.Join(home, DefaultDir), "/tmp/omw.db"},
+		{"OMW_DATA_DIR over config", map[string]string{"OMW_DATA_DIR": "/data"}, "data_dir = \"/config\"\n",
+			"/data", "/data/omw.toml"},
+		{"OMW_FILE", map[string]string{"XDG_DATA_HOME": "/xdg", "OMW_FILE": "old.toml"}, "",
+			"/xdg/omw", "/xdg/omw/old.toml"},
+		{"OMW_DATA_FILE over OMW_FILE", map[string]string{"OMW_DATA_FILE": "/a.toml", "OMW_FILE": "/b.toml"}, "",
+			filepath.Join(home, DefaultDir), "/a.toml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer resetConfig(t, tt.env, tt.config)()
+			dir, file, err := dataPaths()
+			if err != nil {
+				t.Fatalf("dataPaths() error = %v", err)
+			}
+			if dir != tt.wantDir || file != tt.wantFile {
+				t.Errorf("dataPaths() = %s, %s, want %s, %s", dir, file, tt.wantDir, tt.wantFile)
+			}
+		})
+	}
+}
+
+// testClock is a Clock fixed at Wednesday 2020-01-08 12:00 UTC
+type testClock struct{}
+
+func (testClock) Now() time.Time {
+	return time.Date(2020, 1, 8, 12, 0, 0, 0, time.UTC)
+}
+
+func Test_backendOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		// config is the config file
+		config string
+		// wantWeek is the start of this week, which depends on the day
+		// start and week start
+		wantWeek time.Time
+		wantErr  bool
+	}{
+		{"defaults", nil, "", time.Date(2020, 1, 6, 0, 0, 0, 0, time.UTC), false},
+		{"day and week start", nil, "day_start = \"04:00\"\nweek_start = \"sunday\"\n",
+			time.Date(2020, 1, 5, 4, 0, 0, 0, time.UTC), false},
+		{"OMW_WEEK_START over config", map[string]string{"OMW_WEEK_START": "tue"}, "week_start = \"sunday\"\n",
+			time.Date(2020, 1, 7, 0, 0, 0, 0, time.UTC), false},
+		{"OMW_DAY_START", map[string]string{"OMW_DAY_START": "06:30"}, "",
+			time.Date(2020, 1, 6, 6, 30, 0, 0, time.UTC), false},
+		{"categories and periods", nil, "[[categories]]\nname = \"meeting\"\nmarker = \"@@\"\nkind = \"task\"\n\n" +
+			"[[periods]]\nname = \"sprint\"\nstart = 2020-01-06\nlength = \"2w\"\n",
+			time.Date(2020, 1, 6, 0, 0, 0, 0, time.UTC), false},
+		{"invalid day start", nil, "day_start = \"4am\"\n", time.Time{}, true},
+		{"invalid week start", nil, "week_start = \"someday\"\n", time.Time{}, true},
+		{"invalid lock timeout", map[string]string{"OMW_LOCK_TIMEOUT": "soon"}, "", time.Time{}, true},
+		{"invalid category", nil, "[[categories]]\nname = \"nap\"\nmarker = \"zz\"\nkind = \"sleep\"\n", time.Time{}, true},
+		{"invalid period", nil, "[[periods]]\nname = \"sprint\"\nstart = \"someday\"\nlength = \"2w\"\n", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer resetConfig(t, tt.env, tt.config)()
+			opts, err := backendOptions()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("backendOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			b := backend.Create(nil, "/omw", "/omw/omw.toml",
+				append(opts, backend.WithFs(afero.NewMemMapFs()), backend.WithClock(testClock{}))...)
+			week, _, err := b.ParseRange("this week", "this week")
+			if err != nil {
+				t.Fatalf("ParseRange() error = %v", err)
+			}
+			if !week.Equal(tt.wantWeek) {
+				t.Errorf("this week starts %v, want %v", week, tt.wantWeek)
+			}
+		})
+	}
+}
+
+func Test_initConfig_configFlag(t *testing.T) {
+	dir, err := ioutil.TempDir("", "omw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "omw-config.toml")
+	config := "data_dir = \"" + filepath.ToSlash(filepath.Join(dir, "data")) + "\"\n\n[report]\nformat = \"json\"\n"
+	if err := ioutil.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	defer resetConfig(t, map[string]string{"OMW_REPORT_FORMAT": "fc"}, "")()
+	viper.Reset()
+	defer func() { cfgFile = "" }()
+	if err := rootCmd.PersistentFlags().Parse([]string{"--config", path}); err != nil {
+		t.Fatal(err)
+	}
+	defer rootCmd.PersistentFlags().Lookup("config").Value.Set("")
+	initConfig()
+
+	if got := viper.ConfigFileUsed(); got != path {
+		t.Errorf("ConfigFileUsed() = %s, want %s", got, path)
+	}
+	dataDir, dataFile, err := dataPaths()
+	if err != nil || dataDir != filepath.Join(dir, "data") {
+		t.Errorf("dataPaths() = %s, %v, want %s", dataDir, err, filepath.Join(dir, "data"))
+	}
+	if _, err := os.Stat(dataFile); err != nil {
+		t.Errorf("initConfig() didn't create %s: %v", dataFile, err)
+	}
+	if got := viper.GetString(keyReportFmt); got != "fc" {
+		t.Errorf("report format = %q, want OMW_REPORT_FORMAT over the config file", got)
+	}
+}
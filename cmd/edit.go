@@ -22,11 +22,15 @@ import (
 var editCmd = &cobra.Command{
 	Use:   "edit",
 	Short: "Edit your current timesheet",
-	Long:  `Opens a new window to view/edit your current timesheet using your default editor.`,
+	Long: `Opens a new window to view/edit your current timesheet using your default editor.
+
+	Other omw commands keep working while the editor is open.  When you save,
+	your edits are merged with any entries added in the meantime.  If the same
+	entry was changed in both places, nothing is saved and the conflicts are listed.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		reopen, err := server.Edit()
+		reopen, err := server.Edit(ctx)
 		for reopen {
-			reopen, err = server.Edit()
+			reopen, err = server.Edit(ctx)
 			if err != nil {
 				break
 			}
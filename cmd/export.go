@@ -0,0 +1,114 @@
+// Copyright © 2019 David McPike
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// ExportFormat is the file format of omw export, ie: csv, ics or jira
+var ExportFormat string
+
+// Output is the file that omw export writes to, instead of standard output
+var Output string
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the entries of a time range for spreadsheets, calendars, ledgers, org-mode or Jira",
+	Long: `Export writes every task from --from to --to, or in --period, to a
+	file that another program can load.  The range is the
+	same as in omw report, and tasks that run past the start of a day are
+	split between both days.  The csv and ics files have break and ignored
+	time too, with its category, but the timeclock, org and jira formats
+	only log time worked and leave it out.
+
+	--format csv
+
+	writes a row for every task with its ID, start, end, duration in
+	decimal hours, title, category, project, tags, notes and billable flag,
+	in the columns that omw import csv reads back.
+
+	--format ics
+
+	writes an iCalendar file with an event for every task.  The event UID
+	is the entry ID, with the number of the part for tasks split between
+	days, so importing the file again updates the events instead of
+	adding them twice.
+
+	--format timeclock
+
+	writes clock-in and clock-out lines for hledger and ledger, with the
+	project as the account and the title as the description.  Tasks
+	without a project use the timeclock.account from the config file, and
+	a break or ignore category with an account is clocked to that account.
+	Comments before each clock-in keep the entry ID, category, tags,
+	notes and billable flag, so omw import timeclock reads the same
+	entries back.
+
+	--format org
+
+	writes an org-mode outline with a heading for every project and a
+	heading below it for every task, tagged with the tags of the task.
+	Each task has a CLOCK line for every time it was worked on, in a
+	LOGBOOK drawer.
+
+	--format jira|jira-csv
+
+	adds up the time spent on each Jira issue on each day, from the first
+	issue key in each task title, ie: ABC-123, or only the keys of the
+	jira.projects in the config file, and writes a worklog with
+	its started time, timeSpentSeconds and the task titles as comment, as
+	JSON or CSV.  Tasks without an issue key are listed separately, in
+	the unmatched list of the JSON file or with an empty issueKey at the
+	end of the CSV file.`,
+	Example: `
+	omw export --format csv --period "last month" -o timesheet.csv
+	omw export --format ics --from -7d > omw.ics
+	omw export --format csv --period current-pay-period
+	omw export --format timeclock --period "this month" >> ~/ledger/time.timeclock
+	omw export --format org --period "this week" -o ~/org/omw.org
+	omw export --format jira --period yesterday -o worklogs.json
+	`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from, to := From, To
+		if Period != "" {
+			from, to = Period, Period
+		}
+		var w io.Writer = os.Stdout
+		if Output != "" && Output != "-" {
+			f, err := os.Create(Output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		return server.Export(ctx, ExportFormat, from, to, w)
+	},
+}
+
+func init() {
+	exportCmd.Flags().StringVarP(&From, "from", "f", "today", "Beginning date of the export - beginning today if not specified")
+	exportCmd.Flags().StringVarP(&To, "to", "t", "today", "End date of the export - end of today if not specified")
+	exportCmd.Flags().StringVarP(&Period, "period", "p", "", "Day, week, month or named period to export, instead of --from and --to")
+	exportCmd.Flags().StringVarP(&ExportFormat, "format", "a", "csv", "Format of the export - valid values are \"csv\", \"ics\", \"timeclock\", \"org\", \"jira\" and \"jira-csv\"")
+	exportCmd.Flags().StringVarP(&Output, "output", "o", "", "File to write the export to - standard output if not specified")
+	rootCmd.AddCommand(exportCmd)
+}
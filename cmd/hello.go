@@ -31,17 +31,28 @@ var helloCmd = &cobra.Command{
 	It should be run at the beginning of a new work day to signify the 
 	start of your first task.
  
-        If you do not use hello, omw report will calculate the length of your 
-        first task of the day from midnight of the current day.`,
-	Run: func(cmd *cobra.Command, args []string) {
+        If you do not use hello, the first task of the day only marks
+        the start of the day in omw report, like hello does.
+        Use --at or --ago if you forgot to say hello when you started.`,
+	Example: `
+	omw hello
+	omw hello --at 8:45
+	omw hello --ago 15m
+	`,
+	RunE: func(cmd *cobra.Command, args []string) error {
 		if len(args) > 0 {
 			fmt.Fprintf(os.Stderr, "Unused arguments provided after hello command\n")
 			os.Exit(1)
 		}
-		server.Hello()
+		opts, err := entryTime()
+		if err != nil {
+			return err
+		}
+		return server.Hello(ctx, opts...)
 	},
 }
 
 func init() {
+	addTimeFlags(helloCmd)
 	rootCmd.AddCommand(helloCmd)
 }
@@ -0,0 +1,67 @@
+// Copyright © 2019 David McPike
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// HistoryLength is how many operations omw history shows
+var HistoryLength int
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List recent changes to the timesheet",
+	Long: `History lists the most recent changes to the timesheet recorded in
+	the journal, newest first.  Changes marked undone can be applied again
+	with omw redo.`,
+	Example: `
+	omw history
+	omw history -n 50
+	`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ops, err := server.History(ctx)
+		if err != nil {
+			return err
+		}
+		if len(ops) == 0 {
+			fmt.Println("No changes recorded yet")
+			return nil
+		}
+		if HistoryLength > 0 && len(ops) > HistoryLength {
+			ops = ops[:HistoryLength]
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, op := range ops {
+			fmt.Fprintf(w, "%s\t%s\t%s", op.Time.Local().Format("2006-01-02 15:04"), op.Kind, op.Describe())
+			if op.Undone {
+				fmt.Fprint(w, "\t(undone)")
+			}
+			fmt.Fprintln(w)
+		}
+		return w.Flush()
+	},
+}
+
+func init() {
+	historyCmd.Flags().IntVarP(&HistoryLength, "number", "n", 20, "Number of changes to show, 0 for all")
+	rootCmd.AddCommand(historyCmd)
+}
@@ -0,0 +1,112 @@
+// Copyright © 2019 David McPike
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mcdafydd/omw/backend"
+	"github.com/spf13/cobra"
+)
+
+// DryRun shows what would be imported without changing the timesheet
+var DryRun bool
+
+// ImportColumns maps entry fields to the columns of a CSV file
+var ImportColumns map[string]string
+
+// TimeFormat is a Go time layout for the times in a CSV file
+var TimeFormat string
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import <format> <file>",
+	Short: "Import entries from another time tracker",
+	Long: `Import adds the entries from a file saved by another time tracker
+	to your timesheet, in chronological order.  Entries that are already in
+	the timesheet are skipped, so you can import a file again after it grew.
+	Use - as the file to read standard input.  The formats are:
+
+	utt          an Ultimate Time Tracker log, ie: ~/.local/share/utt/utt.log
+	omwlog       a log from omw before the TOML timesheet, omw.log
+	timewarrior  a Timewarrior data file or the output of timew export
+	toggl        a Toggl Track detailed report CSV export
+	clockify     a Clockify detailed report CSV export
+	timeclock    an hledger or ledger timeclock file, ie: from omw export
+	org          the CLOCK lines of an org-mode file
+	csv          any CSV file with a header row
+
+	A CSV file needs a task column and an end time.  Columns named like
+	the fields id, end, start, date, end_date, end_time, start_date,
+	start_time, duration, task, project, tags, notes, billable and category
+	are found by name, and --map names the columns of the other fields.
+
+	In an org-mode file, the task is the heading that a CLOCK line is in
+	and a top-level heading is the project of the headings below it.
+
+	Time trackers that save the start of every task, like Timewarrior, get
+	a hello entry at the start of each day and an untracked entry, which
+	is time to ignore, for each gap between tasks.
+	The timesheet is saved to omw.toml.bak before it is changed, and
+	omw undo removes the imported entries again.`,
+	Example: `
+	omw import utt ~/.local/share/utt/utt.log
+	omw import omwlog ~/.local/share/omw/omw.log --dry-run
+	timew export | omw import timewarrior -
+	omw import toggl Toggl_time_entries.csv
+	omw import timeclock ~/ledger/time.timeclock
+	omw import org ~/org/work.org
+	omw import csv hours.csv --map end=When,task=What,project=Client
+	omw import csv hours.csv --map date=Day,start_time=From,end_time=To,task=Task --time-format "02/01/2006 15:04"
+	`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var r io.Reader = os.Stdin
+		if args[1] != "-" {
+			f, err := os.Open(args[1])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			r = f
+		}
+		result, err := server.Import(ctx, args[0], r, backend.ImportOptions{
+			Columns:    ImportColumns,
+			TimeLayout: TimeFormat,
+			DryRun:     DryRun,
+		})
+		if err != nil {
+			return err
+		}
+		verb := "Imported"
+		if DryRun {
+			verb = "Would import"
+			for _, e := range result.Added {
+				printEntry("Add", e)
+			}
+		}
+		fmt.Printf("%s %d entries, skipped %d already in the timesheet\n", verb, len(result.Added), result.Duplicates)
+		return nil
+	},
+}
+
+func init() {
+	importCmd.Flags().BoolVar(&DryRun, "dry-run", false, "Show the entries that would be imported without saving them")
+	importCmd.Flags().StringToStringVar(&ImportColumns, "map", nil, "Map fields to CSV columns, ie: end=When,task=What")
+	importCmd.Flags().StringVar(&TimeFormat, "time-format", "", "Go time layout of the times in a CSV file, ie: \"02/01/2006 15:04\"")
+	rootCmd.AddCommand(importCmd)
+}
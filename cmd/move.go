@@ -0,0 +1,60 @@
+// Copyright © 2019 David McPike
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/mcdafydd/omw/backend"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// MoveTo is the new end time of the entry, ie: 15:10 or "yesterday 17:05"
+var MoveTo string
+
+// moveCmd represents the move command
+var moveCmd = &cobra.Command{
+	Use:   "move <entry> --to <time>",
+	Short: "Change the time of an entry",
+	Long: `Move changes the end time of a single entry, which also changes
+	where the next task starts.  The entry is moved to keep the timesheet
+	in chronological order.  The time is written like omw add --at.
+	` + refHelp,
+	Example: `
+	omw move last --to 15:10
+	omw move 3f2a --to "yesterday 17:05"
+	omw move --to 2020-01-02T13:00 -- -2
+	`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if MoveTo == "" {
+			return errors.New("missing --to time")
+		}
+		t, err := server.ParseTime(MoveTo)
+		if err != nil {
+			return errors.Wrap(err, "invalid --to")
+		}
+		entry, err := server.AmendEntry(ctx, args[0], backend.EntryAt(t))
+		if err != nil {
+			return err
+		}
+		printEntry("Moved", *entry)
+		return nil
+	},
+}
+
+func init() {
+	moveCmd.Flags().StringVar(&MoveTo, "to", "", "New time of the entry, ie: 15:10, \"yesterday 17:05\" or \"2020-01-02 13:00\"")
+	rootCmd.AddCommand(moveCmd)
+}
@@ -0,0 +1,42 @@
+// Copyright © 2019 David McPike
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// redoCmd represents the redo command
+var redoCmd = &cobra.Command{
+	Use:   "redo",
+	Short: "Apply the last change reverted by undo again",
+	Long: `Redo applies the last change reverted by omw undo again.  Changes
+	can only be redone until the timesheet is changed by another command.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		op, err := server.Redo(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Redid %s %s\n", op.Kind, op.Describe())
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(redoCmd)
+}
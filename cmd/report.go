@@ -16,10 +16,11 @@ package cmd
 
 import (
 	"fmt"
-	"strings"
-	"time"
+	"os"
 
+	"github.com/mcdafydd/omw/backend"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 // From specifies the start date of the report output
@@ -28,43 +29,144 @@ var From string
 // To specified the end date of the report output
 var To string
 
+// Period sets both the start and end of the report, ie: "last week"
+var Period string
+
+// GroupBy nests subtotals by task, project, tag, day or week
+var GroupBy string
+
+// Grid selects a timesheet grid, only "week" is supported
+var Grid string
+
+// GridRows sets the rows of the timesheet grid (task or project)
+var GridRows string
+
+// Round is the increment that durations are rounded to, ie: 15m
+var Round string
+
+// RoundMode rounds to the nearest increment, up or down
+var RoundMode string
+
+// RoundScope rounds every entry or the totals of groups
+var RoundScope string
+
+// CarryOver adds the rounding error to the next duration before rounding
+var CarryOver bool
+
 // Format defines the string output format for the report (text or json)
 var Format = "text"
 
-var defaultTs string
-
 // reportCmd represents the report command
 var reportCmd = &cobra.Command{
 	Use:   "report",
 	Short: "Create a simple report of your most recent task entries",
 	Long: `Report provides options for creating a simple, formatted view
-	of a portion of the tasks in your timesheet.  The default command will 
-	show today's tasks, but you may also specify 
-	
-	--from YYYY-MM-DD --to YYYY-MM-DD 
+	of a portion of the tasks in your timesheet.  The default command will
+	show today's tasks, but you may also specify
+
+	--from <start> --to <end>
+
+	to provide start and optional end dates for the report, or
+
+	--period <range>
+
+	to report on a single day, week, month or named period.
+	If end date is not specified, end date will be today.
+
+	Dates and ranges can be written as:
+
+	2020-01-02, "2020-01-02 13:00", now, today, yesterday,
+	"this week", "last month", "next year", 2020-W03, 2020-Q1,
+	2020-01, 2020, -7d (7 days ago), -2w, -3h
+
+	and as the names of periods from the config file, like
+	current-pay-period or last-pay-period.
 
-	to provide start and optional end dates for the report.
-        If end date is not specified, end date will be today.`,
+	--group-by task|project|tag|day|week
+
+	adds subtotals, nested in the order given, ie: --group-by project,day.
+	Grouping by task adds up every entry with the same title, including
+	stretched tasks.
+
+	--grid week [--rows task|project]
+
+	shows a timesheet for each week, with a row for each task or project,
+	a column for each day and totals in decimal hours, ready to copy into
+	a timesheet form.  Use --format csv to export the timesheet.
+
+	--round 6m|15m|30m [--round-mode nearest|up|down]
+	[--round-scope entry|group] [--carry-over]
+
+	rounds the duration of every entry, or the totals of groups, grid
+	cells and the report, to the increment.  With --carry-over, the
+	rounding error is added to the next duration so totals stay honest.`,
 	Example: `
 	omw report
-	omw report --from 2019-01-01 
+	omw report --from 2019-01-01
 	omw report --from 2019-01-01 --to 2019-01-04
+	omw report --from -7d
+	omw report --period "last week"
+	omw report --period current-pay-period
+	omw report --period "this week" --group-by project,day
+	omw report --period "last week" --group-by task
+	omw report --period "last week" --grid week --rows project
+	omw report --period "last month" --grid week --format csv > timesheet.csv
+	omw report --period "this week" --round 15m --round-mode up
+	omw report --period "this week" --grid week --round 6m --round-scope group --carry-over
 	`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		output, err := server.Report(From, To, Format)
+		from, to := From, To
+		if Period != "" {
+			from, to = Period, Period
+		}
+		groupBy, err := backend.ParseGroupBy(viper.GetString(keyReportGroupBy))
 		if err != nil {
 			return err
 		}
-		fmt.Printf("\n%+v\n", output)
-		return nil
+		opts := []backend.ReportOption{backend.GroupBy(groupBy...)}
+		if Grid != "" {
+			opts = append(opts, backend.WithGrid(Grid, GridRows))
+		}
+		rounding, err := reportRounding()
+		if err != nil {
+			return err
+		}
+		if rounding != nil {
+			opts = append(opts, backend.WithRounding(*rounding))
+		}
+		report, err := server.BuildReport(ctx, from, to, opts...)
+		if err != nil {
+			return err
+		}
+		format := viper.GetString(keyReportFmt)
+		if format != "" && format != "text" {
+			return report.Write(os.Stdout, format)
+		}
+		fmt.Println()
+		err = report.WriteText(os.Stdout)
+		fmt.Println()
+		return err
 	},
 }
 
 func init() {
-	now := time.Now()
-	defaultTs = strings.Fields(now.String())[0] // Should be YYYY-MM-DD
-	reportCmd.Flags().StringVarP(&From, "from", "f", defaultTs, "Beginning date for report output - beginning today if not specified")
-	reportCmd.Flags().StringVarP(&To, "to", "t", defaultTs, "End date for report output - end of today if not specified")
-	reportCmd.Flags().StringVarP(&Format, "format", "a", "text", "Format for report output - valid values are \"text\" or \"json\"")
+	reportCmd.Flags().StringVarP(&From, "from", "f", "today", "Beginning date for report output - beginning today if not specified")
+	reportCmd.Flags().StringVarP(&To, "to", "t", "today", "End date for report output - end of today if not specified")
+	reportCmd.Flags().StringVarP(&Period, "period", "p", "", "Day, week, month or named period to report on, instead of --from and --to")
+	reportCmd.Flags().StringVar(&Grid, "grid", "", "Show a timesheet grid - valid values are \"week\"")
+	reportCmd.Flags().StringVar(&GridRows, "rows", "task", "Rows of the timesheet grid - valid values are \"task\" and \"project\"")
+	reportCmd.Flags().StringVarP(&Format, "format", "a", "text", "Format for report output - valid values are \"text\", \"json\", \"fc\" or \"csv\" (with --grid)")
+	reportCmd.Flags().StringVarP(&GroupBy, "group-by", "g", "", "Comma-separated subtotals - valid values are \"task\", \"project\", \"tag\", \"day\" and \"week\"")
+	// the report config keys are the defaults for --format and --group-by
+	viper.BindPFlag(keyReportFmt, reportCmd.Flags().Lookup("format"))
+	reportCmd.Flags().StringVar(&Round, "round", "", "Round durations to an increment, ie: 6m, 15m or 30m")
+	reportCmd.Flags().StringVar(&RoundMode, "round-mode", backend.RoundNearest, "Rounding mode - valid values are \"nearest\", \"up\" and \"down\"")
+	reportCmd.Flags().StringVar(&RoundScope, "round-scope", backend.RoundEntry, "Round every \"entry\" or the totals of each \"group\"")
+	reportCmd.Flags().BoolVar(&CarryOver, "carry-over", false, "Carry the rounding error over to the next duration")
+	viper.BindPFlag(keyReportGroupBy, reportCmd.Flags().Lookup("group-by"))
+	viper.BindPFlag(keyRound, reportCmd.Flags().Lookup("round"))
+	viper.BindPFlag(keyRoundMode, reportCmd.Flags().Lookup("round-mode"))
+	viper.BindPFlag(keyRoundScope, reportCmd.Flags().Lookup("round-scope"))
+	viper.BindPFlag(keyCarryOver, reportCmd.Flags().Lookup("carry-over"))
 	rootCmd.AddCommand(reportCmd)
 }
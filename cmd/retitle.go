@@ -0,0 +1,47 @@
+// Copyright © 2019 David McPike
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// retitleCmd represents the retitle command
+var retitleCmd = &cobra.Command{
+	Use:   "retitle <entry> <task>",
+	Short: "Replace the task of an entry",
+	Long: `Retitle replaces the task of a single entry, with the same rules as
+	omw add.  A +project in the new task replaces the project of the entry
+	and #tags are added to its tags.
+	` + refHelp,
+	Example: `
+	omw retitle last code review
+	omw retitle 3f2a "lunch **"
+	omw retitle -- -2 "standup +omw #meeting"
+	`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entry, err := server.RetitleEntry(ctx, args[0], args[1:])
+		if err != nil {
+			return err
+		}
+		printEntry("Retitled", *entry)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(retitleCmd)
+}
@@ -0,0 +1,48 @@
+// Copyright © 2019 David McPike
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// rmCmd represents the rm command
+var rmCmd = &cobra.Command{
+	Use:   "rm <entry>...",
+	Short: "Remove entries from the timesheet",
+	Long: `Rm removes one or more entries from your timesheet.  The time of
+	a removed task is added to the task after it in reports.
+	` + refHelp,
+	Example: `
+	omw rm last
+	omw rm 3f2a 9c1e
+	omw rm -- -1 -2
+	`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		removed, err := server.RemoveEntries(ctx, args...)
+		if err != nil {
+			return err
+		}
+		for _, e := range removed {
+			printEntry("Removed", e)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rmCmd)
+}
@@ -15,8 +15,12 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	"github.com/inconshreveable/mousetrap"
 	"github.com/mcdafydd/omw/backend"
@@ -38,6 +42,10 @@ const (
 
 var server *backend.Backend
 
+// ctx is cancelled when omw is interrupted, which stops omw server and
+// any command that is waiting for the timesheet lock
+var ctx = context.Background()
+
 // MousetrapHelpText Set MousetrapHelpText to an empty string to disable Cobra's
 // automatic display of a warning to Windows users who double-click the binary
 // from Windows Explorer.  We want to have our own mousetrap and alias it to
@@ -78,6 +86,15 @@ var rootCmd = &cobra.Command{
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
+	var cancel context.CancelFunc
+	ctx, cancel = context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		cancel()
+	}()
+
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
@@ -87,34 +104,60 @@ func Execute() {
 func init() {
 	cobra.OnInitialize(initConfig)
 
-	home, err := homedir.Dir()
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.omw.toml)")
+	rootCmd.PersistentFlags().String("lock-timeout", backend.DefaultLockTimeout.String(), "How long to wait for another omw process to release the timesheet, ie: 30s")
+	viper.BindPFlag(keyLockTimeout, rootCmd.PersistentFlags().Lookup("lock-timeout"))
+}
+
+// initBackend creates the data file if it is missing and creates the
+// backend using the configuration
+func initBackend() {
+	omwDir, omwFile, err := dataPaths()
 	if err != nil {
-		errors.Wrap(err, "homedir.Dir() returned error")
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 
 	fm := os.FileMode(0700)
-	omwDir := fmt.Sprintf("%s/%s", home, DefaultDir)
 	err = os.MkdirAll(omwDir, fm)
 	if err != nil {
-		errors.Wrapf(err, "MkdirAll %s", omwDir)
+		fmt.Fprintln(os.Stderr, errors.Wrapf(err, "MkdirAll %s", omwDir))
+		os.Exit(1)
 	}
 
-	omwFile := fmt.Sprintf("%s/%s", omwDir, DefaultFile)
 	if _, err := os.Stat(omwFile); os.IsNotExist(err) {
 		fmt.Println("file does not exist - creating file", omwFile)
 		fp, err := os.OpenFile(omwFile, os.O_APPEND|os.O_RDWR|os.O_CREATE, 0644)
 		if err != nil {
-			errors.Wrapf(err, "Can't open or create %s", omwFile)
+			fmt.Fprintln(os.Stderr, errors.Wrapf(err, "Can't open or create %s", omwFile))
+			os.Exit(1)
 		}
 		fp.Close()
 	}
 
-	server = backend.Create(nil, omwDir, omwFile)
+	opts, err := backendOptions()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration in %s: %v\n", configName(), err)
+		os.Exit(1)
+	}
+
+	server = backend.Create(nil, omwDir, omwFile, opts...)
+	recovery, err := server.Recover(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+	}
+	if recovery != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: %s ended with a partially written entry, which was removed:\n\n%s\n\n", recovery.Path, strings.TrimSpace(recovery.Removed))
+		fmt.Fprintf(os.Stderr, "The damaged file was saved as %s - add the entry again with omw add --at, or fix it with omw edit\n", recovery.Damaged)
+	}
+}
 
-	// Here you will define your flags and configuration settings.
-	// Cobra supports persistent flags, which, if defined here,
-	// will be global for your application.
-	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.omw.yaml)")
+// configName describes where the configuration came from in errors
+func configName() string {
+	if viper.ConfigFileUsed() == "" {
+		return "environment"
+	}
+	return viper.ConfigFileUsed()
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -135,10 +178,22 @@ func initConfig() {
 		viper.SetConfigName(".omw")
 	}
 
-	viper.AutomaticEnv() // read in environment variables that match
+	err := setConfigDefaults()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 
-	// If a config file is found, read it in.
-	if err := viper.ReadInConfig(); err == nil {
-		fmt.Println("Using config file:", viper.ConfigFileUsed())
+	// If a config file is found, read it in.  A missing default config
+	// file is fine, but a file given with --config must be readable.
+	err = viper.ReadInConfig()
+	if _, notFound := err.(viper.ConfigFileNotFoundError); err != nil && (cfgFile != "" || !notFound) {
+		fmt.Fprintf(os.Stderr, "can't read config file: %v\n", err)
+		os.Exit(1)
 	}
+	if err == nil {
+		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
+	}
+
+	initBackend()
 }
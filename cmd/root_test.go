@@ -14,7 +14,11 @@
 
 package cmd
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
 
 func TestExecute(t *testing.T) {
 	tests := []struct {
@@ -41,3 +45,42 @@ func Test_initConfig(t *testing.T) {
 		})
 	}
 }
+
+func Test_lockTimeoutFlag(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		env     string
+		want    string
+		wantErr bool
+	}{
+		{"default", nil, "", "10s", false},
+		{"env", nil, "2s", "2s", false},
+		{"flag", []string{"--lock-timeout", "250ms"}, "", "250ms", false},
+		{"flag over env", []string{"--lock-timeout", "250ms"}, "2s", "250ms", false},
+		{"invalid", []string{"--lock-timeout", "soon"}, "", "soon", true},
+	}
+	flag := rootCmd.PersistentFlags().Lookup("lock-timeout")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				flag.Value.Set(flag.DefValue)
+				flag.Changed = false
+			}()
+			env := map[string]string{}
+			if tt.env != "" {
+				env["OMW_LOCK_TIMEOUT"] = tt.env
+			}
+			defer resetConfig(t, env, "")()
+			if err := rootCmd.PersistentFlags().Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			if got := viper.GetString(keyLockTimeout); got != tt.want {
+				t.Errorf("lock timeout = %q, want %q", got, tt.want)
+			}
+			if _, err := backendOptions(); (err != nil) != tt.wantErr {
+				t.Errorf("backendOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright © 2019 David McPike
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/mcdafydd/omw/backend"
+	"github.com/spf13/cobra"
+)
+
+// Addr is the localhost address that omw server listens on
+var Addr string
+
+// AllowOrigin is the browser origin allowed to call the API
+var AllowOrigin string
+
+// serverCmd represents the server command
+var serverCmd = &cobra.Command{
+	Use:   "server",
+	Short: "Serve your timesheet over a local REST API",
+	Long: `Server starts an HTTP server on localhost that lets other programs,
+	like the omw progressive web app or your own scripts, read and write
+	your timesheet without running the omw command.
+
+	GET    /entries          list all entries
+	POST   /entries          add an entry - {"task": "...", "end": "<RFC3339>"}
+	GET    /entries/{id}     get one entry
+	PUT    /entries/{id}     replace one entry
+	DELETE /entries/{id}     remove one entry
+	GET    /report           report with optional from, to, period, group_by,
+	                         grid, rows and format (text, json, fc or csv)
+	                         query parameters
+	GET    /events           FullCalendar event feed with start and end
+	                         query parameters
+
+	The API has no authentication, so the server only listens on the
+	loopback interface and refuses requests for any other host name, like
+	a web page using DNS rebinding.  POST and PUT bodies must be sent with
+	Content-Type: application/json.`,
+	Example: `
+	omw server
+	omw server --addr 127.0.0.1:8080
+	omw server --allow-origin https://omw.example.com
+	`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fmt.Printf("omw server listening on http://%s\n", Addr)
+		return server.Serve(ctx, Addr, AllowOrigin)
+	},
+}
+
+func init() {
+	serverCmd.Flags().StringVarP(&Addr, "addr", "l", backend.DefaultAddr, "Loopback address and port to listen on")
+	serverCmd.Flags().StringVar(&AllowOrigin, "allow-origin", "", "Browser origin allowed to make cross-origin API requests")
+	rootCmd.AddCommand(serverCmd)
+}
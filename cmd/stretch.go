@@ -26,16 +26,27 @@ var stretchCmd = &cobra.Command{
 	Use:   "stretch",
 	Short: "Stretch adds a copy of the most recent task to the timesheet",
 	Long: `Stretch creates a copy of the last entry on your timesheet
-	with the current time, effectively 'stretching' it's total time.`,
+	with the current time, effectively 'stretching' it's total time.
+	With --at or --ago, the last task before that time is stretched to it.`,
+	Example: `
+	omw stretch
+	omw stretch --at 17:30
+	omw stretch --ago 10m
+	`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if len(args) > 0 {
 			fmt.Fprintf(os.Stderr, "Unused arguments provided after stretch command\n")
 			os.Exit(1)
 		}
-		return server.Stretch()
+		opts, err := entryTime()
+		if err != nil {
+			return err
+		}
+		return server.Stretch(ctx, opts...)
 	},
 }
 
 func init() {
+	addTimeFlags(stretchCmd)
 	rootCmd.AddCommand(stretchCmd)
 }
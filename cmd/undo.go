@@ -0,0 +1,49 @@
+// Copyright © 2019 David McPike
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// undoCmd represents the undo command
+var undoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "Revert the last change to the timesheet",
+	Long: `Undo reverts the last change recorded in the journal - an add, hello,
+	stretch, edit, amend, retitle, move or rm.  Run it again to revert the
+	change before that, or run omw redo to apply it again.  A change can't be
+	undone if one of its entries was changed again since.
+	The timesheet is saved to omw.toml.bak before it is changed.`,
+	Example: `
+	omw undo
+	omw history
+	`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		op, err := server.Undo(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Undid %s %s\n", op.Kind, op.Describe())
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(undoCmd)
+}
@@ -15,6 +15,8 @@ import (
 	"github.com/pelletier/go-toml"
 )
 
+// convert prints an omw log from before the TOML timesheet as TOML
+// omw import omwlog adds the log to an existing timesheet instead.
 func main() {
 	layoutEvent := "2006-1-2 15:4"
 	items := backend.SavedItems{}
@@ -39,13 +41,13 @@ func main() {
 		if len(line) < 2 {
 			continue
 		}
-		ts, err := time.Parse(layoutEvent, strings.Join(line[:2], " "))
+		ts, err := time.ParseInLocation(layoutEvent, strings.Join(line[:2], " "), time.Local)
 		if err != nil {
 			continue
 		}
 		entry := strings.Join(line[2:], " ")
 		item.ID = uuid.New().String()
-		item.Start = ts
+		item.End = ts
 		item.Task = entry
 		items.Entries = append(items.Entries, item)
 	}
@@ -55,5 +57,6 @@ func main() {
 	}
 	fmt.Printf("%s", string(b))
 	fmt.Fprintf(os.Stderr, "\nTo finish migrating your data, save the converted output as %s", newPath)
+	fmt.Fprintf(os.Stderr, "\nor add it to your timesheet with: omw import omwlog %s\n", oldPath)
 	return
 }